@@ -0,0 +1,115 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// Returned by the account methods when no user session is given
+var ErrNoSession = errors.New("A TMDb user session is required")
+
+// Whether the user of a session rated, favorited or watchlisted a movie
+type AccountStates struct {
+	Id        ID
+	Favorite  bool
+	Watchlist bool
+	Rated     bool
+	// the user's rating, only set when Rated
+	Rating float64
+}
+
+// TMDb returns rated either as false or as an object with the value
+func (as *AccountStates) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Id        ID
+		Favorite  bool
+		Watchlist bool
+		Rated     json.RawMessage
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*as = AccountStates{Id: raw.Id, Favorite: raw.Favorite, Watchlist: raw.Watchlist}
+	var err error
+	as.Rated, as.Rating, err = parse_rated(raw.Rated)
+	return err
+}
+
+// whether rated (false or an object with the value) holds a rating, and its value
+func parse_rated(rated json.RawMessage) (bool, float64, error) {
+	var rating struct {
+		Value float64
+	}
+	if len(rated) == 0 || rated[0] != '{' {
+		return false, 0, nil
+	}
+	if err := json.Unmarshal(rated, &rating); err != nil {
+		return false, 0, err
+	}
+	return true, rating.Value, nil
+}
+
+// Get the rated/favorite/watchlist states of a movie for the user of the
+// given session, as obtained through TMDb's user authentication
+func (tmdb *TMDb) MovieAccountStates(session_id string, movie_id int) (AccountStates, error) {
+	var states AccountStates
+	if session_id == "" {
+		return states, ErrNoSession
+	}
+	params := url.Values{"session_id": {session_id}}
+	if err := tmdb.get(context.Background(), "/movie/"+strconv.Itoa(movie_id)+"/account_states", params, &states); err != nil {
+		return AccountStates{}, err
+	}
+	return states, nil
+}
+
+// Whether the user of a session rated an episode of a season
+type EpisodeAccountStates struct {
+	Id             ID
+	Episode_number int
+	Rated          bool
+	// the user's rating, only set when Rated
+	Rating float64
+}
+
+func (es *EpisodeAccountStates) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Id             ID
+		Episode_number int
+		Rated          json.RawMessage
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*es = EpisodeAccountStates{Id: raw.Id, Episode_number: raw.Episode_number}
+	var err error
+	es.Rated, es.Rating, err = parse_rated(raw.Rated)
+	return err
+}
+
+// Get the rating states of the episodes of a season of a Tv show for the
+// user of the given session, e.g. for a "continue watching" feature. Empty
+// when TMDb reports no episodes for the season
+func (tmdb *TMDb) TVSeasonAccountStates(session_id string, tv_id, season int) ([]EpisodeAccountStates, error) {
+	if session_id == "" {
+		return nil, ErrNoSession
+	}
+	var resp struct {
+		Results []EpisodeAccountStates
+	}
+	params := url.Values{"session_id": {session_id}}
+	if err := tmdb.get(context.Background(), "/tv/"+strconv.Itoa(tv_id)+"/season/"+strconv.Itoa(season)+"/account_states", params, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Results == nil {
+		return []EpisodeAccountStates{}, nil
+	}
+	return resp.Results, nil
+}
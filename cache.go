@@ -0,0 +1,120 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// A cache of the responses from TMDb, keyed by request. Implementations
+// must be safe for concurrent use
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+}
+
+// Cache the responses from TMDb, so repeated requests are not made again.
+// There is no cache by default
+func (tmdb *TMDb) SetCache(cache Cache) {
+	tmdb.cache = cache
+}
+
+// A cache in memory, with entries expiring after the ttl. Expired entries
+// are removed as they are read, and all of them every ttl as new ones are
+// set, so the cache does not grow without bound
+func NewMemoryCache(ttl time.Duration) Cache {
+	return &memoryCache{clock: realClock{}, ttl: ttl, entries: make(map[string]memoryEntry)}
+}
+
+type memoryCache struct {
+	mu      sync.Mutex
+	clock   clock
+	ttl     time.Duration
+	entries map[string]memoryEntry
+	// when the expired entries were last removed
+	purged time.Time
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+func (c *memoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if c.clock.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *memoryCache) Set(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock.Now()
+	c.entries[key] = memoryEntry{value, now.Add(c.ttl)}
+	// entries that are not read again would otherwise stay forever
+	if now.Sub(c.purged) >= c.ttl {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.purged = now
+	}
+}
+
+type forceRefreshKey struct{}
+
+// A context for calls that must fetch fresh data from TMDb (e.g. when the
+// user asks to refresh metadata) instead of using cached responses. The
+// fresh responses are still written to the cache. For methods that take
+// no context, use SetForceRefresh
+func ForceRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceRefreshKey{}, true)
+}
+
+// Make all the calls fetch fresh data from TMDb instead of using cached
+// responses, like ForceRefresh does for a single call, e.g. while
+// refreshing a whole library. This includes the reference data kept in
+// memory (genres, certifications, watch providers). The
+// fresh responses are still written to the cache
+func (tmdb *TMDb) SetForceRefresh(force bool) {
+	tmdb.refresh = force
+}
+
+// whether cached responses must not be used for the context
+func force_refresh(ctx context.Context) bool {
+	force, _ := ctx.Value(forceRefreshKey{}).(bool)
+	return force
+}
+
+// whether cached responses and reference data may be used for the context
+func (tmdb *TMDb) use_cached(ctx context.Context) bool {
+	return !tmdb.refresh && !force_refresh(ctx)
+}
+
+// The key of a request in the cache: the full path, which holds the ids
+// of nested resources (e.g. "/tv/1/season/1/episode/2"), and all the query
+// parameters in a stable order, so different resources never share a key.
+// Requests made with a user session (e.g. account states) are not cached,
+// "" is returned for them: their answers change with the user's actions
+// and the session id is a credential that must not end up in cache keys,
+// which may be kept in shared or persistent caches
+func cache_key(path string, query url.Values) string {
+	if query.Get("session_id") != "" {
+		return ""
+	}
+	return path + "?" + query.Encode()
+}
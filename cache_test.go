@@ -0,0 +1,159 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheKeyDistinct(t *testing.T) {
+	keys := []string{
+		cache_key("/tv/1/season/1/episode/1", url.Values{}),
+		cache_key("/tv/1/season/1/episode/2", url.Values{}),
+		cache_key("/tv/1/season/2/episode/1", url.Values{}),
+		cache_key("/tv/11/season/1/episode/1", url.Values{}),
+		cache_key("/search/movie", url.Values{"query": {"alien"}}),
+		cache_key("/search/movie", url.Values{"query": {"alien"}, "page": {"2"}}),
+		cache_key("/search/movie", url.Values{"query": {"aliens"}}),
+		cache_key("/search/tv", url.Values{"query": {"alien"}}),
+	}
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if seen[key] {
+			t.Errorf("key %q used for different requests", key)
+		}
+		seen[key] = true
+	}
+}
+
+func TestCacheKeyStable(t *testing.T) {
+	a := cache_key("/search/movie", url.Values{"query": {"alien"}, "page": {"2"}, "language": {"de"}})
+	b := cache_key("/search/movie", url.Values{"language": {"de"}, "page": {"2"}, "query": {"alien"}})
+	if a != b {
+		t.Errorf("same request with different keys %q and %q", a, b)
+	}
+}
+
+func TestCacheKeySession(t *testing.T) {
+	if key := cache_key("/movie/1/account_states", url.Values{"session_id": {"s"}}); key != "" {
+		t.Errorf("session request cached under %q", key)
+	}
+}
+
+func TestCachedEpisodes(t *testing.T) {
+	requests := 0
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		episode := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		fmt.Fprintf(w, `{"id": 1%s, "episode_number": %s}`, episode, episode)
+	})
+	defer done()
+	db.SetCache(NewMemoryCache(time.Hour))
+	for _, episode := range []int{1, 2, 1, 2} {
+		e, err := db.TVEpisode(1, 1, episode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Episode_number != episode {
+			t.Errorf("episode %d: got episode %d", episode, e.Episode_number)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("made %d requests, want 2", requests)
+	}
+}
+
+func TestMemoryCachePurge(t *testing.T) {
+	clock := new_fake_clock()
+	cache := NewMemoryCache(time.Minute).(*memoryCache)
+	cache.clock = clock
+	cache.purged = clock.Now()
+	for i := 0; i < 10; i++ {
+		cache.Set(fmt.Sprint("old", i), []byte("value"))
+	}
+	clock.advance(2 * time.Minute)
+	cache.Set("new", []byte("value"))
+	if len(cache.entries) != 1 {
+		t.Errorf("%d entries left, want only the new one", len(cache.entries))
+	}
+}
+
+func TestSetForceRefresh(t *testing.T) {
+	requests := 0
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"id": 1}`)
+	})
+	defer done()
+	db.SetCache(NewMemoryCache(time.Hour))
+	db.TVEpisode(1, 1, 1)
+	db.SetForceRefresh(true)
+	db.TVEpisode(1, 1, 1)
+	db.SetForceRefresh(false)
+	db.TVEpisode(1, 1, 1)
+	if requests != 2 {
+		t.Errorf("made %d requests, want 2", requests)
+	}
+}
+
+func TestSetForceRefreshReferenceData(t *testing.T) {
+	requests := make(map[string]int)
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		fmt.Fprint(w, `{"genres": [{"id": 18, "name": "Drama"}], "certifications": {"US": []}, "results": []}`)
+	})
+	defer done()
+	db.SetCache(NewMemoryCache(time.Hour))
+	lookups := func() {
+		db.MovieGenreList()
+		db.MovieCertifications()
+		db.MovieReleaseDates(1)
+		db.WatchProviderRegions()
+		db.WatchProvidersList("US")
+	}
+	lookups()
+	lookups()
+	db.SetForceRefresh(true)
+	lookups()
+	for path, n := range requests {
+		if n != 2 {
+			t.Errorf("%s: made %d requests, want 2", path, n)
+		}
+	}
+	if len(requests) != 5 {
+		t.Errorf("requested %d paths, want 5", len(requests))
+	}
+}
+
+func TestReferenceDataCopies(t *testing.T) {
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"results": [{"iso_3166_1": "US"}, {"iso_3166_1": "DE"}],
+			"genres": [{"id": 18, "name": "Drama"}], "certifications": {"US": [{"certification": "G"}]}}`)
+	})
+	defer done()
+
+	regions, _ := db.WatchProviderRegions()
+	regions[0].Iso_3166_1 = "XX"
+	genres, _ := db.MovieGenreList()
+	genres[0].Name = "Changed"
+	certs, _ := db.MovieCertifications()
+	certs["US"][0].Certification = "X"
+	delete(certs, "US")
+
+	if regions, _ := db.WatchProviderRegions(); regions[0].Iso_3166_1 != "US" {
+		t.Errorf("cached regions changed to %v", regions)
+	}
+	if genres, _ := db.MovieGenreList(); genres[0].Name != "Drama" {
+		t.Errorf("cached genres changed to %v", genres)
+	}
+	if certs, _ := db.MovieCertifications(); len(certs["US"]) != 1 || certs["US"][0].Certification != "G" {
+		t.Errorf("cached certifications changed to %v", certs)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// The release dates of a movie in a country
+type ReleaseDates struct {
+	Iso_3166_1    string
+	Release_dates []ReleaseDate
+}
+
+// A release of a movie, with its certification (e.g. "PG-13")
+type ReleaseDate struct {
+	Certification string
+	Iso_639_1     string
+	Note          string
+	Release_date  string
+	// 1 premiere, 2 limited theatrical, 3 theatrical, 4 digital,
+	// 5 physical, 6 tv
+	Type int
+}
+
+// the date ("2014-07-23") a movie opened in theaters in a country, or
+// else of its earliest release there, empty if it has none there
+func region_release_date(releases []ReleaseDates, country string) string {
+	best, best_theatrical := "", false
+	for i := range releases {
+		if releases[i].Iso_3166_1 != country {
+			continue
+		}
+		for _, release := range releases[i].Release_dates {
+			if len(release.Release_date) < 10 {
+				continue
+			}
+			date := release.Release_date[:10]
+			theatrical := release.Type == 3
+			if best == "" || (theatrical && !best_theatrical) || (theatrical == best_theatrical && date < best) {
+				best, best_theatrical = date, theatrical
+			}
+		}
+	}
+	return best
+}
+
+// A certification of a country, the lower the Order the more suitable
+// for all audiences
+type Certification struct {
+	Certification string
+	Meaning       string
+	Order         int
+}
+
+// Get the release dates and certifications of a movie in all countries.
+// They are not kept in memory, as there is an entry per movie, but cached
+// with the other responses when a cache is set (see SetCache)
+func (tmdb *TMDb) MovieReleaseDates(movie_id int) ([]ReleaseDates, error) {
+	var resp struct {
+		Results []ReleaseDates
+	}
+	if err := tmdb.get(context.Background(), "/movie/"+strconv.Itoa(movie_id)+"/release_dates", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// Get the movie certifications of all countries, keyed by country. They
+// are fetched once and cached, like the configuration
+func (tmdb *TMDb) MovieCertifications() (map[string][]Certification, error) {
+	ctx := context.Background()
+	tmdb.cache_mu.Lock()
+	cached := tmdb.certifications
+	tmdb.cache_mu.Unlock()
+	if cached != nil && tmdb.use_cached(ctx) {
+		return copy_certifications(cached), nil
+	}
+	var resp struct {
+		Certifications map[string][]Certification
+	}
+	if err := tmdb.get(ctx, "/certification/movie/list", nil, &resp); err != nil {
+		return nil, err
+	}
+	tmdb.cache_mu.Lock()
+	tmdb.certifications = resp.Certifications
+	tmdb.cache_mu.Unlock()
+	return copy_certifications(resp.Certifications), nil
+}
+
+// a copy of the certifications, so callers may change it without changing
+// the cached ones
+func copy_certifications(certs map[string][]Certification) map[string][]Certification {
+	copied := make(map[string][]Certification, len(certs))
+	for country, c := range certs {
+		copied[country] = append([]Certification(nil), c...)
+	}
+	return copied
+}
+
+// the certification of a movie in a country, empty if it has none there
+func certification(releases []ReleaseDates, country string) string {
+	for i := range releases {
+		if releases[i].Iso_3166_1 != country {
+			continue
+		}
+		for _, release := range releases[i].Release_dates {
+			if release.Certification != "" {
+				return release.Certification
+			}
+		}
+	}
+	return ""
+}
+
+// the order of a certification in a country, -1 if unknown
+func certification_order(certs map[string][]Certification, country, cert string) int {
+	for _, c := range certs[country] {
+		if c.Certification == cert {
+			return c.Order
+		}
+	}
+	return -1
+}
+
+// Search on TMDb for Movies with a given name, like SearchMovie, keeping
+// only the ones certified at most max_cert (e.g. "PG") in the given
+// country (e.g. "US"). Movies without a certification in the country are
+// left out. The release dates of the movies, with their certifications,
+// are fetched for each search unless a cache is set (see SetCache)
+func (tmdb *TMDb) SearchMovieCertified(media_name string, page int, country, max_cert string, opts ...SearchOption) (Response, error) {
+	certs, err := tmdb.MovieCertifications()
+	if err != nil {
+		return Response{}, err
+	}
+	max_order := certification_order(certs, country, max_cert)
+	if max_order < 0 {
+		return Response{}, errors.New("Unknown certification " + max_cert + " for " + country)
+	}
+	resp, err := tmdb.SearchMovie(media_name, page, opts...)
+	if err != nil {
+		return Response{}, err
+	}
+	var results []Result
+	for _, r := range resp.Results {
+		releases, err := tmdb.MovieReleaseDates(int(r.Id))
+		if err != nil {
+			return Response{}, err
+		}
+		order := certification_order(certs, country, certification(releases, country))
+		if order >= 0 && order <= max_order {
+			results = append(results, r)
+		}
+	}
+	resp.Results = results
+	return resp, nil
+}
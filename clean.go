@@ -0,0 +1,84 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Cleans up part of the noise in a media file or folder name before it is
+// used as a search query
+type QueryCleaner func(name string) string
+
+// The cleaners CleanQuery uses by default, in order
+var DefaultCleaners = []QueryCleaner{NormalizeSeparators, StripReleaseGroup, StripReleaseTags, NormalizeSequel}
+
+// Clean up a media file or folder name for searching, e.g. "Rocky.Part.II.1080p.BluRay.x264-GRP"
+// becomes "Rocky 2". The cleaners are applied in order, DefaultCleaners if none are given
+func CleanQuery(name string, cleaners ...QueryCleaner) string {
+	if len(cleaners) == 0 {
+		cleaners = DefaultCleaners
+	}
+	for _, clean := range cleaners {
+		name = clean(name)
+	}
+	return collapse_spaces(name)
+}
+
+func collapse_spaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Turn dots and underscores used as word separators into spaces
+func NormalizeSeparators(name string) string {
+	return collapse_spaces(strings.NewReplacer(".", " ", "_", " ").Replace(name))
+}
+
+var (
+	bracketed = regexp.MustCompile(`[\[{][^\]}]*[\]}]`)
+	// a group with some upper case, right after a codec, resolution or
+	// source tag, so title words as in "spider-man" or "X-Men" are kept
+	release_group = regexp.MustCompile(`(?i:\b(2160p|1080p|1080i|720p|576p|480p|4k|bluray|bdrip|brrip|dvdrip|webrip|web-dl|webdl|hdtv|hdrip|x264|x265|h264|h265|hevc|xvid|divx|aac|ac3|dts|remux))-[[:alnum:]]*[[:upper:]][[:alnum:]]*$`)
+)
+
+// Remove the release group, as in "[YTS] Title" or "Title x264-GROUP", and
+// other bracketed junk
+func StripReleaseGroup(name string) string {
+	name = bracketed.ReplaceAllString(name, " ")
+	return collapse_spaces(release_group.ReplaceAllString(strings.TrimSpace(name), "$1"))
+}
+
+var release_tags = regexp.MustCompile(`(?i)\b(2160p|1080p|1080i|720p|576p|480p|4k|uhd|hdr|bluray|blu-ray|bdrip|brrip|dvdrip|dvdscr|webrip|web-dl|webdl|hdtv|hdrip|x264|x265|h264|h265|hevc|xvid|divx|aac|ac3|dts|remux|proper|repack|unrated)\b`)
+
+// Remove resolution, source, codec and audio tags (1080p, BluRay, x264, ...)
+// and anything after the first of them
+func StripReleaseTags(name string) string {
+	if loc := release_tags.FindStringIndex(name); loc != nil {
+		name = name[:loc[0]]
+	}
+	return collapse_spaces(name)
+}
+
+var (
+	roman_numbers = map[string]string{
+		"ii": "2", "iii": "3", "iv": "4", "v": "5", "vi": "6", "vii": "7", "viii": "8", "ix": "9", "x": "10",
+	}
+	sequel_part = regexp.MustCompile(`(?i)\bpart\s+(ii|iii|iv|v|vi|vii|viii|ix|x|\d+)\b`)
+)
+
+// Normalize sequel numbers, "Part II" and "Part 2" become "2". Standalone
+// numerals are kept, as in "Rocky IV", "Richard III" or "World War II",
+// which TMDb knows by them
+func NormalizeSequel(name string) string {
+	name = sequel_part.ReplaceAllStringFunc(name, func(part string) string {
+		number := sequel_part.FindStringSubmatch(part)[1]
+		if arabic, ok := roman_numbers[strings.ToLower(number)]; ok {
+			return arabic
+		}
+		return number
+	})
+	return collapse_spaces(name)
+}
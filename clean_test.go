@@ -0,0 +1,75 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"testing"
+)
+
+func test_cleaner(t *testing.T, name string, clean QueryCleaner, tests map[string]string) {
+	for in, want := range tests {
+		if got := clean(in); got != want {
+			t.Errorf("%s(%q) = %q, want %q", name, in, got, want)
+		}
+	}
+}
+
+func TestNormalizeSeparators(t *testing.T) {
+	test_cleaner(t, "NormalizeSeparators", NormalizeSeparators, map[string]string{
+		"The.Matrix.1999":  "The Matrix 1999",
+		"the_matrix__1999": "the matrix 1999",
+		"Already Clean":    "Already Clean",
+		" Mr. Robot  S01 ": "Mr Robot S01",
+	})
+}
+
+func TestStripReleaseGroup(t *testing.T) {
+	test_cleaner(t, "StripReleaseGroup", StripReleaseGroup, map[string]string{
+		"Movie 2019 1080p BluRay x264-GRP":   "Movie 2019 1080p BluRay x264",
+		"Movie 2019 1080p WEB-DL-NTb":        "Movie 2019 1080p WEB-DL",
+		"[YTS] Movie 2019":                   "Movie 2019",
+		"Movie {tag} 2019":                   "Movie 2019",
+		"spider-man":                         "spider-man",
+		"Spider-Man":                         "Spider-Man",
+		"x-men":                              "x-men",
+		"X-Men":                              "X-Men",
+		"Movie 2019 x264-grp":                "Movie 2019 x264-grp",
+		"Avengers Age of Ultron 720p-SPARKS": "Avengers Age of Ultron 720p",
+	})
+}
+
+func TestStripReleaseTags(t *testing.T) {
+	test_cleaner(t, "StripReleaseTags", StripReleaseTags, map[string]string{
+		"Movie 2019 1080p BluRay x264": "Movie 2019",
+		"Movie 2019 WEBRip":            "Movie 2019",
+		"Movie Unrated Cut":            "Movie",
+		"No Tags Here":                 "No Tags Here",
+	})
+}
+
+func TestNormalizeSequel(t *testing.T) {
+	test_cleaner(t, "NormalizeSequel", NormalizeSequel, map[string]string{
+		"Rocky Part II":   "Rocky 2",
+		"Rocky part 3":    "Rocky 3",
+		"Rocky IV":        "Rocky IV",
+		"Richard III":     "Richard III",
+		"Henry VIII":      "Henry VIII",
+		"World War II":    "World War II",
+		"Malcolm X":       "Malcolm X",
+		"Planet V Attack": "Planet V Attack",
+	})
+}
+
+func TestCleanQuery(t *testing.T) {
+	test_cleaner(t, "CleanQuery", func(name string) string { return CleanQuery(name) }, map[string]string{
+		"Rocky.Part.II.1080p.BluRay.x264-GRP": "Rocky 2",
+		"spider-man.2002.720p":                "spider-man 2002",
+		"X-Men.2000.DVDRip.XviD-FLAWL3SS":     "X-Men 2000",
+		"[YTS] The.Matrix.1999.1080p":         "The Matrix 1999",
+	})
+	if got := CleanQuery("Some.Movie", NormalizeSeparators); got != "Some Movie" {
+		t.Errorf("CleanQuery with a single cleaner = %q", got)
+	}
+}
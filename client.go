@@ -0,0 +1,222 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Use the given HTTP client for all requests to TMDb instead of
+// http.DefaultClient, e.g. to set timeouts or tune the connection pool of
+// its transport. For high throughput scanning use something like
+//
+//	db.SetHTTPClient(&http.Client{Transport: tmdb.HighThroughputTransport()})
+func (tmdb *TMDb) SetHTTPClient(client *http.Client) {
+	tmdb.client = client
+}
+
+// the client requests are made with
+func (tmdb *TMDb) http_client() *http.Client {
+	if tmdb.client == nil {
+		return http.DefaultClient
+	}
+	return tmdb.client
+}
+
+// A transport keeping enough idle connections to TMDb around to make many
+// concurrent requests without reconnecting. The default transport only
+// keeps 2 idle connections per host, its other settings (e.g. timeouts)
+// are kept
+func HighThroughputTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = 32
+	return transport
+}
+
+// Timeouts for the different kinds of requests, on top of any deadline of
+// the context of the call. Kinds left at zero are only bound by the
+// timeout of the HTTP client
+type Timeouts struct {
+	// searches, usually user facing and best failing fast
+	Search time.Duration
+	// details of movies, tv shows, etc. and everything else
+	Details time.Duration
+	// the configuration, cached and fine being slow
+	Config time.Duration
+}
+
+// Set the timeouts for the different kinds of requests
+func (tmdb *TMDb) SetTimeouts(timeouts Timeouts) {
+	tmdb.timeouts = timeouts
+}
+
+// the timeout for a request to the path, 0 for none
+func (tmdb *TMDb) timeout_for(path string) time.Duration {
+	switch {
+	case strings.HasPrefix(path, "/search/"):
+		return tmdb.timeouts.Search
+	case path == "/configuration":
+		return tmdb.timeouts.Config
+	}
+	return tmdb.timeouts.Details
+}
+
+// Limit the requests made to TMDb to the given number per period, e.g.
+// 40 every 10 seconds, spacing them evenly. Requests wait for their turn
+// (or until their context is done), the ones made with a LowPriority
+// context after the others. There is no limit by default
+func (tmdb *TMDb) SetRateLimit(requests int, per time.Duration) {
+	if requests <= 0 {
+		tmdb.limiter = nil
+		return
+	}
+	tmdb.limiter = &rateLimiter{interval: per / time.Duration(requests), clock: tmdb.time()}
+}
+
+// spaces requests by interval. When requests have to wait, the ones with
+// high priority get the free slots first, in the order they came
+type rateLimiter struct {
+	mu       sync.Mutex
+	clock    clock
+	interval time.Duration
+	// when the next request may be made
+	next time.Time
+	// the requests waiting for a slot, by priority
+	high, low []chan struct{}
+	// whether slots are being handed out to waiting requests
+	dispatching bool
+}
+
+type lowPriorityKey struct{}
+
+// A context for calls of low priority, e.g. of background scans of a
+// library, for the methods taking a context (e.g. MovieDataByIDsContext,
+// DiscoverMoviesContext, AllPagesContext). When the rate limit is reached,
+// calls waiting without it (e.g. made by the user) are served first
+func LowPriority(ctx context.Context) context.Context {
+	return context.WithValue(ctx, lowPriorityKey{}, true)
+}
+
+func low_priority(ctx context.Context) bool {
+	low, _ := ctx.Value(lowPriorityKey{}).(bool)
+	return low
+}
+
+// wait for the turn of a request
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := l.clock.Now()
+	if len(l.high) == 0 && len(l.low) == 0 && !l.next.After(now) {
+		l.next = now.Add(l.interval)
+		l.mu.Unlock()
+		return nil
+	}
+	turn := make(chan struct{})
+	if low_priority(ctx) {
+		l.low = append(l.low, turn)
+	} else {
+		l.high = append(l.high, turn)
+	}
+	if !l.dispatching {
+		l.dispatching = true
+		go l.dispatch()
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-turn:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		select {
+		case <-turn:
+			// the slot was handed out meanwhile, give it back
+			l.next = l.next.Add(-l.interval)
+		default:
+			l.high = remove_turn(l.high, turn)
+			l.low = remove_turn(l.low, turn)
+		}
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// hand out the slots to the waiting requests, high priority first, until
+// none are left
+func (l *rateLimiter) dispatch() {
+	for {
+		l.mu.Lock()
+		clock := l.clock
+		delay := l.next.Sub(clock.Now())
+		l.mu.Unlock()
+		if delay > 0 {
+			clock.Sleep(delay)
+		}
+
+		l.mu.Lock()
+		var turn chan struct{}
+		switch {
+		case len(l.high) > 0:
+			turn, l.high = l.high[0], l.high[1:]
+		case len(l.low) > 0:
+			turn, l.low = l.low[0], l.low[1:]
+		default:
+			l.dispatching = false
+			l.mu.Unlock()
+			return
+		}
+		l.next = l.clock.Now().Add(l.interval)
+		close(turn)
+		l.mu.Unlock()
+	}
+}
+
+func remove_turn(turns []chan struct{}, turn chan struct{}) []chan struct{} {
+	for i := range turns {
+		if turns[i] == turn {
+			return append(turns[:i], turns[i+1:]...)
+		}
+	}
+	return turns
+}
+
+type extraParamsKey struct{}
+
+// A context for calls that send extra query parameters to TMDb, e.g. ones
+// added to the API before this library models them. They never replace
+// the parameters set by the library, nor the api_key. For methods that
+// take no context, use SetExtraParams
+func ExtraParams(ctx context.Context, params url.Values) context.Context {
+	return context.WithValue(ctx, extraParamsKey{}, params)
+}
+
+// Send extra query parameters with all the requests to TMDb, like
+// ExtraParams does for a single call. The ones given to a call take
+// precedence over these
+func (tmdb *TMDb) SetExtraParams(params url.Values) {
+	tmdb.extra_params = params
+}
+
+// add the extra query parameters of the context, then of the TMDb, the
+// query does not have yet
+func (tmdb *TMDb) add_extra_params(ctx context.Context, query url.Values) {
+	extra, _ := ctx.Value(extraParamsKey{}).(url.Values)
+	for _, params := range []url.Values{extra, tmdb.extra_params} {
+		for k := range params {
+			if k == "api_key" {
+				continue
+			}
+			if _, ok := query[k]; !ok {
+				query[k] = params[k]
+			}
+		}
+	}
+}
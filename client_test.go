@@ -0,0 +1,137 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExtraParams(t *testing.T) {
+	var query url.Values
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		fmt.Fprint(w, `{"results": []}`)
+	})
+	defer done()
+	db.SetExtraParams(url.Values{"region": {"US"}, "include_adult": {"false"}, "api_key": {"stolen"}})
+	ctx := ExtraParams(context.Background(), url.Values{"region": {"DE"}, "query": {"other"}})
+	db.searchMovie(ctx, "Alien", 0)
+	if query.Get("api_key") != "key" {
+		t.Errorf("api_key replaced by %q", query.Get("api_key"))
+	}
+	if query.Get("query") != "Alien" {
+		t.Errorf("query replaced by %q", query.Get("query"))
+	}
+	if query.Get("region") != "DE" {
+		t.Errorf("got region %q, want the one of the call", query.Get("region"))
+	}
+	if query.Get("include_adult") != "false" {
+		t.Errorf("extra parameter of the TMDb not sent")
+	}
+}
+
+// a fake clock whose sleeps each wait for a step, to hand out the slots
+// of a rate limiter one at a time
+type stepClock struct {
+	*fakeClock
+	steps chan struct{}
+}
+
+func (c stepClock) Sleep(d time.Duration) {
+	<-c.steps
+	c.fakeClock.Sleep(d)
+}
+
+// wait until the limiter has n requests waiting with high and low priority
+func waiting(l *rateLimiter, high, low int) {
+	for {
+		l.mu.Lock()
+		done := len(l.high) == high && len(l.low) == low
+		l.mu.Unlock()
+		if done {
+			return
+		}
+		runtime.Gosched()
+	}
+}
+
+func TestLimiterPriority(t *testing.T) {
+	clock := stepClock{new_fake_clock(), make(chan struct{})}
+	l := &rateLimiter{interval: time.Second, clock: clock}
+	// takes the free slot
+	l.wait(context.Background())
+	var low_served int32
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.wait(LowPriority(context.Background())); err == nil {
+				atomic.AddInt32(&low_served, 1)
+			}
+		}()
+	}
+	waiting(l, 0, 3)
+	high_served := make(chan struct{})
+	go func() {
+		if err := l.wait(context.Background()); err == nil {
+			close(high_served)
+		}
+	}()
+	waiting(l, 1, 3)
+
+	// the next slot
+	clock.steps <- struct{}{}
+	<-high_served
+	if n := atomic.LoadInt32(&low_served); n != 0 {
+		t.Errorf("%d low priority requests served before the high priority one", n)
+	}
+	for i := 0; i < 3; i++ {
+		clock.steps <- struct{}{}
+	}
+	wg.Wait()
+	// lets the dispatcher find the queues empty and stop
+	close(clock.steps)
+	if n := atomic.LoadInt32(&low_served); n != 3 {
+		t.Errorf("%d low priority requests served, want 3", n)
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	l := &rateLimiter{interval: time.Hour, clock: realClock{}}
+	l.wait(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx); err == nil {
+		t.Fatal("expected the wait to be cancelled")
+	}
+	l.mu.Lock()
+	waiting := len(l.high) + len(l.low)
+	l.mu.Unlock()
+	if waiting != 0 {
+		t.Errorf("%d cancelled requests still waiting", waiting)
+	}
+}
+
+func TestHighThroughputTransport(t *testing.T) {
+	transport := HighThroughputTransport()
+	if transport.MaxIdleConnsPerHost != 32 {
+		t.Errorf("got %d idle connections per host, want 32", transport.MaxIdleConnsPerHost)
+	}
+	if transport.DialContext == nil || transport.Proxy == nil || transport.ExpectContinueTimeout == 0 || transport.TLSHandshakeTimeout == 0 {
+		t.Error("settings of the default transport were dropped")
+	}
+	if transport == http.DefaultTransport {
+		t.Error("the default transport itself was returned")
+	}
+}
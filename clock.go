@@ -0,0 +1,54 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"time"
+)
+
+// the source of time of the rate limiter, the retry budget and the memory
+// cache, replaced in tests to advance time without sleeping
+type clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
+
+// the wall clock
+type realClock struct{}
+
+func (realClock) Now() time.Time        { return time.Now() }
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// the clock of the TMDb, the wall clock unless replaced
+func (tmdb *TMDb) time() clock {
+	if tmdb.clock == nil {
+		return realClock{}
+	}
+	return tmdb.clock
+}
+
+// replace the clock of the TMDb and of its rate limiter, retry budget and
+// memory cache
+func (tmdb *TMDb) set_clock(c clock) {
+	tmdb.clock = c
+	if tmdb.limiter != nil {
+		tmdb.limiter.mu.Lock()
+		tmdb.limiter.clock = c
+		tmdb.limiter.mu.Unlock()
+	}
+	if tmdb.budget != nil {
+		tmdb.budget.mu.Lock()
+		tmdb.budget.clock = c
+		tmdb.budget.mu.Unlock()
+	}
+	if cache, ok := tmdb.cache.(*memoryCache); ok {
+		cache.mu.Lock()
+		cache.clock = c
+		cache.mu.Unlock()
+	}
+}
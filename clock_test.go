@@ -0,0 +1,109 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a clock that only moves when slept on or advanced
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	slept time.Duration
+}
+
+func new_fake_clock() *fakeClock {
+	return &fakeClock{now: time.Now()}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.slept += d
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.Sleep(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	requests := 0
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(429)
+		w.Write([]byte(`{"status_code": 25, "status_message": "Too many requests"}`))
+	})
+	defer done()
+	clock := new_fake_clock()
+	db.set_clock(clock)
+	db.SetRetries(3)
+	_, err := db.TVEpisode(1, 1, 1)
+	var retry_err *RetryError
+	if !errors.As(err, &retry_err) {
+		t.Fatalf("got %v, want a *RetryError", err)
+	}
+	if retry_err.Attempts() != 4 || retry_err.LastStatus() != 429 {
+		t.Errorf("got %d attempts with status %d, want 4 with 429", retry_err.Attempts(), retry_err.LastStatus())
+	}
+	if requests != 4 {
+		t.Errorf("made %d requests, want 4", requests)
+	}
+	// doubling from half a second
+	if want := 3500 * time.Millisecond; clock.slept != want {
+		t.Errorf("backed off for %v, want %v", clock.slept, want)
+	}
+}
+
+func TestRetryBudgetRefill(t *testing.T) {
+	clock := new_fake_clock()
+	budget := new_retry_budget(1, clock)
+	if !budget.take() {
+		t.Fatal("full budget refused a retry")
+	}
+	if budget.take() {
+		t.Fatal("spent budget allowed a retry")
+	}
+	clock.advance(time.Second)
+	if !budget.take() {
+		t.Fatal("budget not refilled after a second")
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	clock := new_fake_clock()
+	db := Init("key")
+	db.SetCache(NewMemoryCache(time.Minute))
+	db.set_clock(clock)
+	db.cache.Set("key", []byte("value"))
+	clock.advance(30 * time.Second)
+	if _, ok := db.cache.Get("key"); !ok {
+		t.Error("entry expired before its ttl")
+	}
+	clock.advance(time.Minute)
+	if _, ok := db.cache.Get("key"); ok {
+		t.Error("entry still cached after its ttl")
+	}
+}
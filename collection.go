@@ -0,0 +1,33 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"sort"
+	"strconv"
+)
+
+// A collection of movies, e.g. a franchise, with its movies
+type Collection struct {
+	Id            ID
+	Name          string
+	Overview      string
+	Poster_path   string
+	Backdrop_path string
+	// the movies of the collection
+	Parts []Result
+}
+
+// Get a collection (e.g. found with SearchCollection, or the
+// Belongs_to_collection of a movie) with its movies, in release order
+func (tmdb *TMDb) CollectionData(collection_id int) (Collection, error) {
+	var c Collection
+	if err := tmdb.get(context.Background(), "/collection/"+strconv.Itoa(collection_id), nil, &c); err != nil {
+		return Collection{}, err
+	}
+	sort.Stable(byReleaseDate{c.Parts, false})
+	return c, nil
+}
@@ -0,0 +1,192 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// crew jobs kept first when limiting the crew, in this order
+var key_jobs = []string{"Director", "Screenplay", "Writer", "Story", "Producer"}
+
+// Limit the credits in the metadata to the top billed cast and crew,
+// 0 keeping all of them. The crew keeps the key jobs (Director, Writer,
+// etc.) first
+func (tmdb *TMDb) SetCreditsLimit(cast, crew int) {
+	tmdb.cast_limit = cast
+	tmdb.crew_limit = crew
+}
+
+// Use the best voted profile picture of each of the top n billed cast
+// members in the metadata, instead of the one TMDb credits them with. The
+// pictures are looked up a few at a time, one request per cast member, and
+// the credited one is kept when that fails. 0, the default, looks up none
+func (tmdb *TMDb) SetBestCastPhotos(top_n int) {
+	tmdb.best_photos = top_n
+}
+
+// lookups of profile pictures made at once by best_cast_photos
+const photo_concurrency = 4
+
+// replace the profile pictures of the top billed cast with their best
+// voted ones
+func (tmdb *TMDb) best_cast_photos(ctx context.Context, cast []Cast) {
+	n := tmdb.best_photos
+	if n > len(cast) {
+		n = len(cast)
+	}
+	queue := make(chan *Cast)
+	var wg sync.WaitGroup
+	for i := 0; i < photo_concurrency && i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range queue {
+				images, err := tmdb.person_images(ctx, int(c.Id))
+				if err != nil {
+					continue
+				}
+				// profile pictures have no language
+				if best := most_voted(images, ""); best != nil {
+					c.Profile_path = best.File_path
+				}
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		queue <- &cast[i]
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// Keep only the crew of the given departments (e.g. "Directing",
+// "Writing") in the metadata, for compact displays of the key roles. No
+// departments keeps the whole crew
+func (tmdb *TMDb) SetCrewDepartments(departments ...string) {
+	tmdb.crew_departments = departments
+}
+
+// the credits with only the crew of the departments (all for none)
+func (c Credits) in_departments(departments []string) Credits {
+	if len(departments) == 0 {
+		return c
+	}
+	var crew []Crew
+	for _, member := range c.Crew {
+		for _, department := range departments {
+			if member.Department == department {
+				crew = append(crew, member)
+				break
+			}
+		}
+	}
+	c.Crew = crew
+	return c
+}
+
+// the credits with at most cast and crew entries (0 for all)
+func (c Credits) limited(cast, crew int) Credits {
+	if cast > 0 && len(c.Cast) > cast {
+		c.Cast = c.Cast[:cast]
+	}
+	if crew > 0 && len(c.Crew) > crew {
+		sorted := make([]Crew, len(c.Crew))
+		copy(sorted, c.Crew)
+		sort.Stable(byKeyJob(sorted))
+		c.Crew = sorted[:crew]
+	}
+	return c
+}
+
+// rank of the job of a crew member, the key jobs first
+func job_rank(job string) int {
+	for i := range key_jobs {
+		if key_jobs[i] == job {
+			return i
+		}
+	}
+	return len(key_jobs)
+}
+
+// sorts crew by the key jobs first
+type byKeyJob []Crew
+
+func (s byKeyJob) Len() int           { return len(s) }
+func (s byKeyJob) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byKeyJob) Less(i, j int) bool { return job_rank(s[i].Job) < job_rank(s[j].Job) }
+
+// Credits with the profile pictures resolved to full URLs, ready to render
+type ResolvedCredits struct {
+	Cast []ResolvedCast
+	Crew []ResolvedCrew
+}
+
+// A cast member with the full URL of the profile picture, empty when
+// TMDb has no picture
+type ResolvedCast struct {
+	Cast
+	FullProfileURL string
+}
+
+// A crew member with the full URL of the profile picture, empty when
+// TMDb has no picture
+type ResolvedCrew struct {
+	Crew
+	FullProfileURL string
+}
+
+// Resolve the profile pictures of all the cast and crew of a movie to
+// full URLs with the given size (the preferred one if empty), using the
+// configuration that came with the movie metadata
+func (tmdb *TMDb) ResolvedCredits(md MovieMetadata, profile_size string) ResolvedCredits {
+	var rc ResolvedCredits
+	if profile_size == "" {
+		profile_size = tmdb.sizes.Profile
+	}
+	profile_url := func(path string) string {
+		if path == "" || md.Config == nil {
+			return ""
+		}
+		return md.Config.Images.Base_url + image_size(md.Config.Images.Profile_sizes, profile_size) + path
+	}
+	rc.Cast = make([]ResolvedCast, len(md.Credits.Cast))
+	for i, cast := range md.Credits.Cast {
+		rc.Cast[i] = ResolvedCast{cast, profile_url(cast.Profile_path)}
+	}
+	rc.Crew = make([]ResolvedCrew, len(md.Credits.Crew))
+	for i, crew := range md.Credits.Crew {
+		rc.Crew[i] = ResolvedCrew{crew, profile_url(crew.Profile_path)}
+	}
+	return rc
+}
+
+// The crew grouped by department (e.g. "Directing", "Writing"), keeping
+// their order within each department. Encoded as JSON, the departments
+// come out sorted
+func (c Credits) ByDepartment() map[string][]Crew {
+	departments := make(map[string][]Crew)
+	for _, crew := range c.Crew {
+		departments[crew.Department] = append(departments[crew.Department], crew)
+	}
+	return departments
+}
+
+// The distinct departments of the crew, sorted, e.g. to offer filters
+// for ByDepartment
+func (c Credits) Departments() []string {
+	seen := make(map[string]bool)
+	var departments []string
+	for _, crew := range c.Crew {
+		if !seen[crew.Department] {
+			seen[crew.Department] = true
+			departments = append(departments, crew.Department)
+		}
+	}
+	sort.Strings(departments)
+	return departments
+}
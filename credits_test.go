@@ -0,0 +1,52 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBestCastPhotosBounded(t *testing.T) {
+	var mu sync.Mutex
+	in_flight, most, requests := 0, 0, 0
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		in_flight++
+		if in_flight > most {
+			most = in_flight
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		in_flight--
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"profiles": [{"file_path": "/best.jpg", "vote_average": 5}]}`))
+	})
+	defer done()
+	db.SetBestCastPhotos(50)
+
+	cast := make([]Cast, 20)
+	for i := range cast {
+		cast[i].Id = ID(i + 1)
+	}
+	db.best_cast_photos(context.Background(), cast)
+	if requests != len(cast) {
+		t.Errorf("made %d requests, want %d", requests, len(cast))
+	}
+	if most > photo_concurrency {
+		t.Errorf("made %d requests at once, want at most %d", most, photo_concurrency)
+	}
+	for i := range cast {
+		if cast[i].Profile_path != "/best.jpg" {
+			t.Errorf("cast %d: got profile %q, want /best.jpg", i, cast[i].Profile_path)
+		}
+	}
+}
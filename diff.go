@@ -0,0 +1,105 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+// A field that changed between two snapshots of metadata. Old and New
+// hold the values of plain fields. For list fields (genres, cast, crew)
+// they are left nil and Added and Removed hold the entries that changed
+type FieldChange struct {
+	Field   string
+	Old     interface{}
+	New     interface{}
+	Added   []string
+	Removed []string
+}
+
+// Compare two snapshots of the metadata of a movie, e.g. stored and
+// refreshed, and return the fields that changed, in a fixed order. Genres
+// are compared by name, cast members by name and character, crew members
+// by name and job
+func DiffMetadata(before, after MovieMetadata) []FieldChange {
+	var changes []FieldChange
+	plain := func(field string, o, n string) {
+		if o != n {
+			changes = append(changes, FieldChange{Field: field, Old: o, New: n})
+		}
+	}
+	plain("title", before.Title, after.Title)
+	plain("overview", before.Overview, after.Overview)
+	plain("release_date", before.Release_date, after.Release_date)
+	plain("imdb_id", before.Imdb_id, after.Imdb_id)
+	plain("poster_path", before.Poster_path, after.Poster_path)
+	plain("backdrop_path", before.Backdrop_path, after.Backdrop_path)
+	if before.Vote_average != after.Vote_average {
+		changes = append(changes, FieldChange{Field: "vote_average", Old: before.Vote_average, New: after.Vote_average})
+	}
+
+	var old_collection, new_collection ID
+	if before.Belongs_to_collection != nil {
+		old_collection = before.Belongs_to_collection.Id
+	}
+	if after.Belongs_to_collection != nil {
+		new_collection = after.Belongs_to_collection.Id
+	}
+	if old_collection != new_collection {
+		changes = append(changes, FieldChange{Field: "belongs_to_collection", Old: old_collection, New: new_collection})
+	}
+
+	list := func(field string, o, n []string) {
+		added, removed := diff_lists(o, n)
+		if len(added) > 0 || len(removed) > 0 {
+			changes = append(changes, FieldChange{Field: field, Added: added, Removed: removed})
+		}
+	}
+	list("genres", genre_entries(before.Genres), genre_entries(after.Genres))
+	list("cast", cast_entries(before.Credits.Cast), cast_entries(after.Credits.Cast))
+	list("crew", crew_entries(before.Credits.Crew), crew_entries(after.Credits.Crew))
+	return changes
+}
+
+func genre_entries(genres []Genre) []string {
+	entries := make([]string, len(genres))
+	for i := range genres {
+		entries[i] = genres[i].Name
+	}
+	return entries
+}
+
+func cast_entries(cast []Cast) []string {
+	entries := make([]string, len(cast))
+	for i := range cast {
+		entries[i] = cast[i].Name + " as " + cast[i].Character
+	}
+	return entries
+}
+
+func crew_entries(crew []Crew) []string {
+	entries := make([]string, len(crew))
+	for i := range crew {
+		entries[i] = crew[i].Name + " (" + crew[i].Job + ")"
+	}
+	return entries
+}
+
+// the entries only in n and the ones only in o, in their original order
+func diff_lists(o, n []string) (added, removed []string) {
+	in_old := make(map[string]bool)
+	for _, e := range o {
+		in_old[e] = true
+	}
+	in_new := make(map[string]bool)
+	for _, e := range n {
+		in_new[e] = true
+		if !in_old[e] {
+			added = append(added, e)
+		}
+	}
+	for _, e := range o {
+		if !in_new[e] {
+			removed = append(removed, e)
+		}
+	}
+	return added, removed
+}
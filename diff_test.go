@@ -0,0 +1,33 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffMetadata(t *testing.T) {
+	before := MovieMetadata{
+		Title:        "Movie",
+		Vote_average: 7.1,
+		Genres:       []Genre{{18, "Drama"}, {53, "Thriller"}},
+		Credits:      Credits{Cast: []Cast{{Name: "A", Character: "X"}}},
+	}
+	after := before
+	after.Vote_average = 7.3
+	after.Genres = []Genre{{18, "Drama"}, {80, "Crime"}}
+
+	want := []FieldChange{
+		{Field: "vote_average", Old: 7.1, New: 7.3},
+		{Field: "genres", Added: []string{"Crime"}, Removed: []string{"Thriller"}},
+	}
+	if got := DiffMetadata(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := DiffMetadata(before, before); len(got) != 0 {
+		t.Errorf("got %+v for the same metadata, want no changes", got)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Filters for discovering movies. Fields left at their zero value are
+// not sent to TMDb, except IncludeAdult which is always sent
+type DiscoverOptions struct {
+	// e.g. "popularity.desc" or "release_date.asc"
+	SortBy string
+	// include adult (pornographic) movies
+	IncludeAdult       bool
+	PrimaryReleaseYear int
+	// ISO 639-1 code of the original language, e.g. "ja"
+	WithOriginalLanguage string
+	// genre ids the movies have, all of them unless GenresAny is set
+	WithGenres []int
+	GenresAny  bool
+	// ids of the companies that produced the movies, any of them
+	WithCompanies []int
+	// keyword ids the movies have, all of them (joined with "," for TMDb)
+	// unless KeywordsAny is set, then any of them (joined with "|")
+	WithKeywords []int
+	KeywordsAny  bool
+	// keyword ids the movies must not have, movies with any of them are
+	// left out
+	WithoutKeywords []int
+	// runtime range in minutes
+	WithRuntimeGte int
+	WithRuntimeLte int
+	// minimum number of votes, e.g. 200 so ratings are meaningful when
+	// sorting by "vote_average.desc"
+	VoteCountGte int
+	// country of the certifications, e.g. "US", required for the
+	// certification filters below
+	CertificationCountry string
+	Certification        string
+	CertificationLte     string
+}
+
+// genres suitable for children: Animation and Family
+var kids_genres = []int{16, 10751}
+
+// Preset of discover options for a children's profile: no adult movies,
+// certified at most max_cert in the given country and within the
+// kid-friendly genres. More filters can be set on the returned options
+func KidsMode(country string, max_cert string) DiscoverOptions {
+	return DiscoverOptions{
+		IncludeAdult:         false,
+		CertificationCountry: country,
+		CertificationLte:     max_cert,
+		// a copy, so changing it does not change the preset
+		WithGenres: append([]int(nil), kids_genres...),
+		GenresAny:  true,
+	}
+}
+
+// join ids as TMDb expects, comma separated when all of them must match
+// and pipe separated when any of them may
+func join_ids(ids []int, or bool) string {
+	sep := ","
+	if or {
+		sep = "|"
+	}
+	s := make([]string, len(ids))
+	for i := range ids {
+		s[i] = strconv.Itoa(ids[i])
+	}
+	return strings.Join(s, sep)
+}
+
+// check the options make sense together
+func (opts *DiscoverOptions) validate() error {
+	if opts.WithRuntimeGte != 0 && opts.WithRuntimeLte != 0 && opts.WithRuntimeGte > opts.WithRuntimeLte {
+		return errors.New("Discover runtime minimum is over the maximum")
+	}
+	return nil
+}
+
+// the query parameters for the options
+func (opts *DiscoverOptions) params() url.Values {
+	p := url.Values{}
+	p.Set("include_adult", strconv.FormatBool(opts.IncludeAdult))
+	if opts.SortBy != "" {
+		p.Set("sort_by", opts.SortBy)
+	}
+	if opts.PrimaryReleaseYear != 0 {
+		p.Set("primary_release_year", strconv.Itoa(opts.PrimaryReleaseYear))
+	}
+	if opts.WithOriginalLanguage != "" {
+		p.Set("with_original_language", opts.WithOriginalLanguage)
+	}
+	if len(opts.WithGenres) > 0 {
+		p.Set("with_genres", join_ids(opts.WithGenres, opts.GenresAny))
+	}
+	if len(opts.WithCompanies) > 0 {
+		p.Set("with_companies", join_ids(opts.WithCompanies, true))
+	}
+	if len(opts.WithKeywords) > 0 {
+		p.Set("with_keywords", join_ids(opts.WithKeywords, opts.KeywordsAny))
+	}
+	if len(opts.WithoutKeywords) > 0 {
+		p.Set("without_keywords", join_ids(opts.WithoutKeywords, false))
+	}
+	if opts.WithRuntimeGte != 0 {
+		p.Set("with_runtime.gte", strconv.Itoa(opts.WithRuntimeGte))
+	}
+	if opts.WithRuntimeLte != 0 {
+		p.Set("with_runtime.lte", strconv.Itoa(opts.WithRuntimeLte))
+	}
+	if opts.VoteCountGte != 0 {
+		p.Set("vote_count.gte", strconv.Itoa(opts.VoteCountGte))
+	}
+	if opts.CertificationCountry != "" {
+		p.Set("certification_country", opts.CertificationCountry)
+	}
+	if opts.Certification != "" {
+		p.Set("certification", opts.Certification)
+	}
+	if opts.CertificationLte != "" {
+		p.Set("certification.lte", opts.CertificationLte)
+	}
+	return p
+}
+
+// Discover movies on TMDb matching the given filters, returning the given page of results
+func (tmdb *TMDb) DiscoverMovies(opts DiscoverOptions, page int) (Response, error) {
+	return tmdb.DiscoverMoviesContext(context.Background(), opts, page)
+}
+
+// DiscoverMovies within a context, e.g. a LowPriority one for background
+// browsing
+func (tmdb *TMDb) DiscoverMoviesContext(ctx context.Context, opts DiscoverOptions, page int) (Response, error) {
+	var resp Response
+	if err := opts.validate(); err != nil {
+		return resp, err
+	}
+	params := opts.params()
+	set_page(params, page)
+	if err := tmdb.get(ctx, "/discover/movie", params, &resp); err != nil {
+		return Response{}, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,18 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"testing"
+)
+
+func TestKidsModeCopy(t *testing.T) {
+	opts := KidsMode("US", "PG")
+	opts.WithGenres[0] = 28
+	opts.WithGenres = append(opts.WithGenres, 35)
+	if genres := KidsMode("US", "PG").WithGenres; len(genres) != 2 || genres[0] != 16 || genres[1] != 10751 {
+		t.Errorf("preset genres changed to %v", genres)
+	}
+}
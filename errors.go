@@ -0,0 +1,94 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Returned along with the metadata when not in strict mode and some of its
+// sections could not be fetched. The metadata is usable, with the failed
+// sections left empty
+type PartialError struct {
+	Sections []SectionError
+}
+
+// The error fetching a section of the metadata, e.g. "credits"
+type SectionError struct {
+	Section string
+	Err     error
+}
+
+func (e *PartialError) Error() string {
+	msgs := make([]string, len(e.Sections))
+	for i, s := range e.Sections {
+		msgs[i] = s.Section + ": " + s.Err.Error()
+	}
+	return "Incomplete metadata from TMDb, " + strings.Join(msgs, "; ")
+}
+
+// Returned when a request still fails after being retried (see
+// SetRetries), or when its retry was given up as it could not be made
+// before the deadline of its context, wrapping the error of the last
+// attempt
+type RetryError struct {
+	Err         error
+	attempts    int
+	last_status int
+}
+
+func (e *RetryError) Error() string {
+	return e.Err.Error() + " (after " + strconv.Itoa(e.attempts) + " attempts)"
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// The number of attempts made, including the first one
+func (e *RetryError) Attempts() int {
+	return e.attempts
+}
+
+// The HTTP status of the last response, e.g. 429 or 503, even when the
+// last attempt then failed without one (e.g. on a network error)
+func (e *RetryError) LastStatus() int {
+	return e.last_status
+}
+
+// In strict mode (the default) MovieData and TVData fail when any part of
+// the metadata cannot be fetched. Otherwise, once the details are fetched,
+// they return the metadata they could assemble along with a *PartialError
+func (tmdb *TMDb) SetStrict(strict bool) {
+	tmdb.lenient = !strict
+}
+
+// collects the errors of sections of the metadata
+type sectionErrors struct {
+	lenient bool
+	errs    []SectionError
+}
+
+// whether to go on after fetching the section with the error, which is
+// collected when lenient
+func (se *sectionErrors) ok(section string, err error) bool {
+	if err == nil {
+		return true
+	}
+	if !se.lenient {
+		return false
+	}
+	se.errs = append(se.errs, SectionError{section, err})
+	return true
+}
+
+// the error for all the sections that failed, nil if none
+func (se *sectionErrors) err() error {
+	if len(se.errs) == 0 {
+		return nil
+	}
+	return &PartialError{se.errs}
+}
@@ -0,0 +1,61 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// where TMDb publishes its daily exports of valid ids
+const export_url = "http://files.tmdb.org/p/exports/"
+
+// Get the ids of all the valid movies on TMDb, out of the export TMDb
+// publishes for the given day (made available around 8:00 UTC, and kept
+// for three months), e.g. for full catalog crawls. The export is streamed
+// through the configured client, without the rate limit as it is not an
+// API request. Adult movies are included
+func (tmdb *TMDb) MovieIDExport(date time.Time) ([]int, error) {
+	req, err := http.NewRequest("GET", export_url+"movie_ids_"+date.UTC().Format("01_02_2006")+".json.gz", nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tmdb.http_client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, error_status(res.StatusCode)
+	}
+	gz, err := gzip.NewReader(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	// one JSON object per line
+	var ids []int
+	lines := bufio.NewScanner(gz)
+	for lines.Scan() {
+		if len(lines.Bytes()) == 0 {
+			continue
+		}
+		var entry struct {
+			Id ID
+		}
+		if err := json.Unmarshal(lines.Bytes(), &entry); err != nil {
+			return nil, err
+		}
+		ids = append(ids, int(entry.Id))
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"errors"
+)
+
+// A genre of movies or tv shows
+type Genre struct {
+	Id   ID     `json:"id"`
+	Name string `json:"name"`
+}
+
+// Get the genres of movies. They are fetched once and cached
+func (tmdb *TMDb) MovieGenreList() ([]Genre, error) {
+	return tmdb.genre_list("/genre/movie/list", &tmdb.movie_genres)
+}
+
+// Get the genres of tv shows, which differ from the ones of movies. They
+// are fetched once and cached
+func (tmdb *TMDb) TVGenreList() ([]Genre, error) {
+	return tmdb.genre_list("/genre/tv/list", &tmdb.tv_genres)
+}
+
+// the genres at the path, cached in the list
+func (tmdb *TMDb) genre_list(path string, list *[]Genre) ([]Genre, error) {
+	ctx := context.Background()
+	tmdb.cache_mu.Lock()
+	cached := *list
+	tmdb.cache_mu.Unlock()
+	if cached != nil && tmdb.use_cached(ctx) {
+		return append([]Genre(nil), cached...), nil
+	}
+	var resp struct {
+		Genres []Genre
+	}
+	if err := tmdb.get(ctx, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	tmdb.cache_mu.Lock()
+	*list = resp.Genres
+	tmdb.cache_mu.Unlock()
+	return append([]Genre(nil), resp.Genres...), nil
+}
+
+// The names of the genres of a result (its Genre_ids), out of the genres
+// of movies or tv shows as media_type is "movie" or "tv". Unknown genres
+// are skipped
+func (tmdb *TMDb) GenreNames(media_type string, genre_ids []int) ([]string, error) {
+	var genres []Genre
+	var err error
+	switch media_type {
+	case "movie":
+		genres, err = tmdb.MovieGenreList()
+	case "tv":
+		genres, err = tmdb.TVGenreList()
+	default:
+		return nil, errors.New("Unknown media type " + media_type + ", must be movie or tv")
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[ID]string)
+	for _, g := range genres {
+		names[g.Id] = g.Name
+	}
+	var resolved []string
+	for _, id := range genre_ids {
+		if name, ok := names[ID(id)]; ok {
+			resolved = append(resolved, name)
+		}
+	}
+	return resolved, nil
+}
@@ -0,0 +1,548 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	// poster formats
+	_ "image/jpeg"
+	_ "image/png"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The images of a movie, tv show, season or person
+type Images struct {
+	Id        ID
+	Backdrops []Image
+	Posters   []Image
+	Profiles  []Image
+	Stills    []Image
+}
+
+// A single image, the File_path is relative to the configured base url
+type Image struct {
+	Aspect_ratio float64
+	File_path    string
+	Height       int
+	Width        int
+	Iso_639_1    string
+	Vote_average float64
+	Vote_count   int
+}
+
+// Preferred image sizes, e.g. "w342" for posters. Sizes left empty use
+// the library defaults
+type ImageSizePrefs struct {
+	Poster   string
+	Backdrop string
+	Profile  string
+	Still    string
+}
+
+// default width of the poster
+const default_poster_size = "w154"
+
+// Set the preferred image sizes used when assembling image URLs
+func (tmdb *TMDb) SetImageSizes(prefs ImageSizePrefs) {
+	tmdb.sizes = prefs
+}
+
+// the available sizes of the given kind of image and the preferred one
+func (tmdb *TMDb) sizes_for(config *Config, kind string) ([]string, string) {
+	switch kind {
+	case "poster":
+		if tmdb.sizes.Poster == "" {
+			return config.Images.Poster_sizes, default_poster_size
+		}
+		return config.Images.Poster_sizes, tmdb.sizes.Poster
+	case "backdrop":
+		return config.Images.Backdrop_sizes, tmdb.sizes.Backdrop
+	case "profile":
+		return config.Images.Profile_sizes, tmdb.sizes.Profile
+	case "still":
+		return config.Images.Still_sizes, tmdb.sizes.Still
+	case "logo":
+		return config.Images.Logo_sizes, ""
+	}
+	return nil, ""
+}
+
+// Full URL for an image of the given kind, with the given size or the
+// preferred one when size is empty, falling back to the closest size
+// available. Images with no path get an empty URL
+func (tmdb *TMDb) image_url(path, kind, size string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	config, err := tmdb.getConfig(context.Background())
+	if err != nil {
+		return "", err
+	}
+	sizes, preferred := tmdb.sizes_for(config, kind)
+	if size == "" {
+		size = preferred
+	}
+	return config.Images.Base_url + image_size(sizes, size) + path, nil
+}
+
+// Validate an image size for a kind of image ("poster", "backdrop",
+// "profile", "still" or "logo") against the sizes TMDb serves, returning
+// it in canonical form: "W500" and "500" become "w500". Unknown sizes are
+// an error, unlike in the URL helpers which fall back to another size
+func (tmdb *TMDb) CanonicalSize(kind, size string) (string, error) {
+	config, err := tmdb.getConfig(context.Background())
+	if err != nil {
+		return "", err
+	}
+	switch kind {
+	case "poster", "backdrop", "profile", "still", "logo":
+	default:
+		return "", errors.New("Unknown image kind " + kind)
+	}
+	sizes, _ := tmdb.sizes_for(config, kind)
+	canonical := strings.ToLower(strings.TrimSpace(size))
+	if _, err := strconv.Atoi(canonical); err == nil {
+		canonical = "w" + canonical
+	}
+	for i := range sizes {
+		if sizes[i] == canonical {
+			return canonical, nil
+		}
+	}
+	return "", errors.New(fmt.Sprintf("Unknown %s size %q, must be one of %s", kind, size, strings.Join(sizes, ", ")))
+}
+
+// Full URL for a poster with the given size, or the preferred one if empty
+func (tmdb *TMDb) PosterURL(poster_path string, size string) (string, error) {
+	return tmdb.image_url(poster_path, "poster", size)
+}
+
+// Full URL for a backdrop with the given size, or the preferred one if empty
+func (tmdb *TMDb) BackdropURL(backdrop_path string, size string) (string, error) {
+	return tmdb.image_url(backdrop_path, "backdrop", size)
+}
+
+// Full URL for a profile picture with the given size, or the preferred one if empty
+func (tmdb *TMDb) ProfileURL(profile_path string, size string) (string, error) {
+	return tmdb.image_url(profile_path, "profile", size)
+}
+
+// Full URL for an episode still with the given size, or the preferred
+// one if empty. Stills with no path get an empty URL
+func (tmdb *TMDb) StillURL(still_path string, size string) (string, error) {
+	return tmdb.image_url(still_path, "still", size)
+}
+
+// Full URLs for the stills of all the episodes of a season, in the order
+// of its episodes, with the given size or the preferred one if empty.
+// Episodes without a still get an empty URL
+func (tmdb *TMDb) SeasonStillURLs(season TVSeason, size string) ([]string, error) {
+	config, err := tmdb.getConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	sizes, preferred := tmdb.sizes_for(config, "still")
+	if size == "" {
+		size = preferred
+	}
+	size = image_size(sizes, size)
+	urls := make([]string, len(season.Episodes))
+	for i, e := range season.Episodes {
+		if e.Still_path != "" {
+			urls[i] = config.Images.Base_url + size + e.Still_path
+		}
+	}
+	return urls, nil
+}
+
+// Full URLs for the posters of all the movies of a collection, in the
+// order of its Parts, with the given size or the preferred one if empty.
+// Movies without a poster get an empty URL
+func (tmdb *TMDb) CollectionPartPosterURLs(collection Collection, size string) ([]string, error) {
+	config, err := tmdb.getConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	sizes, preferred := tmdb.sizes_for(config, "poster")
+	if size == "" {
+		size = preferred
+	}
+	size = image_size(sizes, size)
+	urls := make([]string, len(collection.Parts))
+	for i, part := range collection.Parts {
+		if part.Poster_path != "" {
+			urls[i] = config.Images.Base_url + size + part.Poster_path
+		}
+	}
+	return urls, nil
+}
+
+// Full URL for the poster of the collection the movie is part of, with the
+// given size or the preferred one if empty. Empty when the movie is not
+// part of a collection
+func (tmdb *TMDb) CollectionPosterURL(md MovieMetadata, size string) (string, error) {
+	if md.Belongs_to_collection == nil {
+		return "", nil
+	}
+	return tmdb.PosterURL(md.Belongs_to_collection.Poster_path, size)
+}
+
+// Full URL of the most voted poster of a movie in the given language
+// (ISO 639-1 code, e.g. "de"), or else of the most voted poster without
+// text, with the preferred size. Empty when the movie has neither
+func (tmdb *TMDb) PosterForLanguage(movie_id int, lang string) (string, error) {
+	var images Images
+	params := url.Values{"include_image_language": {lang + ",null"}}
+	if err := tmdb.get(context.Background(), "/movie/"+strconv.Itoa(movie_id)+"/images", params, &images); err != nil {
+		return "", err
+	}
+	poster := most_voted(images.Posters, lang)
+	if poster == nil {
+		poster = most_voted(images.Posters, "")
+	}
+	if poster == nil {
+		return "", nil
+	}
+	return tmdb.PosterURL(poster.File_path, "")
+}
+
+// the image in the given language with the best votes, nil if none.
+// Images without text have an empty language
+func most_voted(images []Image, lang string) *Image {
+	var best *Image
+	for i := range images {
+		image := &images[i]
+		if image.Iso_639_1 != lang {
+			continue
+		}
+		if best == nil || image.Vote_average > best.Vote_average ||
+			(image.Vote_average == best.Vote_average && image.Vote_count > best.Vote_count) {
+			best = image
+		}
+	}
+	return best
+}
+
+// An image resolved at one size
+type ImageVariant struct {
+	Size string
+	// width in pixels parsed from the size ("w342" is 342), 0 for
+	// "original" and sizes given by height
+	Width int
+	URL   string
+}
+
+// the width of a size like "w342", 0 if not a width
+func size_width(size string) int {
+	if !strings.HasPrefix(size, "w") {
+		return 0
+	}
+	width, err := strconv.Atoi(size[1:])
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// Full URL for a backdrop at the smallest size at least min_width pixels
+// wide, or the original if none is that wide, so it is not upscaled.
+// Backdrops with no path get an empty URL
+func (tmdb *TMDb) BackdropForWidth(backdrop_path string, min_width int) (string, error) {
+	if backdrop_path == "" {
+		return "", nil
+	}
+	config, err := tmdb.getConfig(context.Background())
+	if err != nil {
+		return "", err
+	}
+	size, width := "original", 0
+	for _, offered := range config.Images.Backdrop_sizes {
+		w := size_width(offered)
+		if w > 0 && w >= min_width && (width == 0 || w < width) {
+			size, width = offered, w
+		}
+	}
+	return config.Images.Base_url + size + backdrop_path, nil
+}
+
+// Resolve a poster to full URLs at each of the given sizes, e.g. to build
+// a srcset. Sizes not offered for posters are skipped. Posters with no
+// path get no variants
+func (tmdb *TMDb) PosterSrcSet(poster_path string, sizes []string) ([]ImageVariant, error) {
+	if poster_path == "" {
+		return nil, nil
+	}
+	config, err := tmdb.getConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	var variants []ImageVariant
+	for _, size := range sizes {
+		for _, offered := range config.Images.Poster_sizes {
+			if offered == size {
+				variants = append(variants, ImageVariant{size, size_width(size), config.Images.Base_url + size + poster_path})
+				break
+			}
+		}
+	}
+	return variants, nil
+}
+
+// Full URLs for profile pictures (e.g. from PersonImages) with the given
+// size, or the preferred one if empty, in the same order
+func (tmdb *TMDb) ProfileImageURLs(images []Image, size string) ([]string, error) {
+	urls := make([]string, len(images))
+	for i := range images {
+		var err error
+		if urls[i], err = tmdb.ProfileURL(images[i].File_path, size); err != nil {
+			return nil, err
+		}
+	}
+	return urls, nil
+}
+
+// Download an image (or any file) through the configured client, within
+// the rate limit and the timeout for details
+func (tmdb *TMDb) download(ctx context.Context, url string) ([]byte, error) {
+	if tmdb.timeouts.Details > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, tmdb.timeouts.Details)
+		defer cancel()
+	}
+	if tmdb.limiter != nil {
+		if err := tmdb.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tmdb.http_client().Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, error_status(res.StatusCode)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// Download the profile pictures of all the cast and crew with the given
+// size (the preferred one if empty), with up to concurrency downloads at
+// once. The pictures are keyed by the person's name, people without a
+// picture are skipped. On failure the pictures downloaded so far are
+// returned along with the first error
+func (tmdb *TMDb) PrefetchProfiles(credits Credits, size string, concurrency int) (map[string][]byte, error) {
+	paths := make(map[string]string)
+	var names []string
+	add := func(name, path string) {
+		if _, ok := paths[name]; !ok && path != "" {
+			paths[name] = path
+			names = append(names, name)
+		}
+	}
+	for _, c := range credits.Cast {
+		add(c.Name, c.Profile_path)
+	}
+	for _, c := range credits.Crew {
+		add(c.Name, c.Profile_path)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	// fetch the config before the workers need it
+	if _, err := tmdb.getConfig(context.Background()); err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	var first_err error
+	profiles := make(map[string][]byte)
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				data, err := tmdb.download_profile(paths[name], size)
+				mu.Lock()
+				if err != nil && first_err == nil {
+					first_err = err
+				} else if err == nil {
+					profiles[name] = data
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, name := range names {
+		jobs <- name
+	}
+	close(jobs)
+	wg.Wait()
+	return profiles, first_err
+}
+
+func (tmdb *TMDb) download_profile(path, size string) ([]byte, error) {
+	url, err := tmdb.ProfileURL(path, size)
+	if err != nil {
+		return nil, err
+	}
+	return tmdb.download(context.Background(), url)
+}
+
+// The artwork of a movie, keyed by the path of each image
+type ArtBundle struct {
+	Posters   map[string][]byte
+	Backdrops map[string][]byte
+}
+
+// downloads made at once by DownloadAllArt
+const art_concurrency = 4
+
+// Download all the posters and backdrops of a movie with the given sizes
+// (the preferred ones if empty), e.g. to archive them. When languages (ISO
+// 639-1 codes, "" for images without text) are given, only the images in
+// those languages are downloaded. Downloads are made concurrently, within
+// the rate limit. On failure the images downloaded so far are returned
+// along with the first error
+func (tmdb *TMDb) DownloadAllArt(movie_id int, poster_size, backdrop_size string, languages ...string) (ArtBundle, error) {
+	return tmdb.DownloadAllArtContext(context.Background(), movie_id, poster_size, backdrop_size, languages...)
+}
+
+// DownloadAllArt within a context, e.g. a LowPriority one for archiving
+// in the background. Once the context is done, the downloads left fail
+func (tmdb *TMDb) DownloadAllArtContext(ctx context.Context, movie_id int, poster_size, backdrop_size string, languages ...string) (ArtBundle, error) {
+	var images Images
+	if err := tmdb.get(ctx, "/movie/"+strconv.Itoa(movie_id)+"/images", nil, &images); err != nil {
+		return ArtBundle{}, err
+	}
+	wanted := func(image Image) bool {
+		if len(languages) == 0 {
+			return true
+		}
+		for _, lang := range languages {
+			if image.Iso_639_1 == lang {
+				return true
+			}
+		}
+		return false
+	}
+	type art struct {
+		images map[string][]byte
+		path   string
+		url    string
+	}
+	bundle := ArtBundle{Posters: make(map[string][]byte), Backdrops: make(map[string][]byte)}
+	var jobs []art
+	for _, image := range images.Posters {
+		if wanted(image) {
+			url, err := tmdb.PosterURL(image.File_path, poster_size)
+			if err != nil {
+				return ArtBundle{}, err
+			}
+			jobs = append(jobs, art{bundle.Posters, image.File_path, url})
+		}
+	}
+	for _, image := range images.Backdrops {
+		if wanted(image) {
+			url, err := tmdb.BackdropURL(image.File_path, backdrop_size)
+			if err != nil {
+				return ArtBundle{}, err
+			}
+			jobs = append(jobs, art{bundle.Backdrops, image.File_path, url})
+		}
+	}
+
+	var mu sync.Mutex
+	var first_err error
+	queue := make(chan art)
+	var wg sync.WaitGroup
+	for i := 0; i < art_concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				data, err := tmdb.download(ctx, job.url)
+				mu.Lock()
+				if err != nil && first_err == nil {
+					first_err = err
+				} else if err == nil {
+					job.images[job.path] = data
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+	return bundle, first_err
+}
+
+// Returned when a movie has no poster
+var ErrNoPoster = errors.New("No poster found at TMDb")
+
+// Download the poster of a movie with the given size, or the preferred one
+// if empty. Returns ErrNoPoster if the movie has none
+func (tmdb *TMDb) FetchPoster(movie_id int, size string) ([]byte, error) {
+	ctx := context.Background()
+	md, err := tmdb.getMovieDetails(ctx, strconv.Itoa(movie_id), false)
+	if err != nil {
+		return nil, err
+	}
+	if md.Poster_path == "" {
+		return nil, ErrNoPoster
+	}
+	url, err := tmdb.PosterURL(md.Poster_path, size)
+	if err != nil {
+		return nil, err
+	}
+	return tmdb.download(ctx, url)
+}
+
+// The average color of the poster of a movie, downloaded with the given
+// size (the preferred one if empty), e.g. to tint the background behind
+// it. Returns ErrNoPoster if the movie has none
+func (tmdb *TMDb) PosterAverageColor(movie_id int, size string) (color.RGBA, error) {
+	data, err := tmdb.FetchPoster(movie_id, size)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	var r, g, b, n uint64
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pr, pg, pb, _ := img.At(x, y).RGBA()
+			r += uint64(pr >> 8)
+			g += uint64(pg >> 8)
+			b += uint64(pb >> 8)
+			n++
+		}
+	}
+	if n == 0 {
+		return color.RGBA{}, errors.New("Empty poster image")
+	}
+	return color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), 255}, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"strings"
+	"unicode"
+)
+
+// letters folded to their base letters when matching titles
+var diacritics = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ł': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ș': "s", 'ß': "ss", 'ť': "t", 'ţ': "t", 'ț': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z", 'þ': "th",
+}
+
+// Fold a title for matching: lowercased, with diacritics stripped (so
+// "Amélie" matches "Amelie") and punctuation turned into single spaces.
+// Only used to compare titles, queries are sent to TMDb as given
+func normalize_title(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// combining marks of already decomposed letters
+		case diacritics[r] != "":
+			b.WriteString(diacritics[r])
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// The index of the result that best matches the query: the first one
+// with a title matching it once normalized, or else the first one.
+// Results less popular than min_popularity are only picked when no
+// result is popular enough
+func best_match(query string, results []Result, min_popularity float64) int {
+	popular := func(r *Result) bool { return r.Popularity >= min_popularity }
+	any_popular := false
+	for i := range results {
+		if popular(&results[i]) {
+			any_popular = true
+			break
+		}
+	}
+	if !any_popular {
+		popular = func(*Result) bool { return true }
+	}
+	q := normalize_title(query)
+	first := -1
+	for i := range results {
+		r := &results[i]
+		if !popular(r) {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		for _, title := range []string{r.Title, r.Original_title, r.Name, r.Original_name} {
+			if title != "" && normalize_title(title) == q {
+				return i
+			}
+		}
+	}
+	if first < 0 {
+		return 0
+	}
+	return first
+}
+
+// Set the popularity below which search results are ignored when picking
+// the match for MovieData, as long as a more popular result exists. This
+// keeps obscure entries with the same title from being matched. 0, the
+// default, considers all results
+func (tmdb *TMDb) SetMinPopularity(min_popularity float64) {
+	tmdb.min_popularity = min_popularity
+}
@@ -0,0 +1,413 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Get the given page of the movies TMDb recommends for a movie
+func (tmdb *TMDb) MovieRecommendations(movie_id int, page int) (Response, error) {
+	var resp Response
+	params := url.Values{}
+	set_page(params, page)
+	if err := tmdb.get(context.Background(), "/movie/"+strconv.Itoa(movie_id)+"/recommendations", params, &resp); err != nil {
+		return Response{}, err
+	}
+	return resp, nil
+}
+
+// Get the given page of the movies similar to a movie
+func (tmdb *TMDb) MovieSimilar(movie_id int, page int) (Response, error) {
+	var resp Response
+	params := url.Values{}
+	set_page(params, page)
+	if err := tmdb.get(context.Background(), "/movie/"+strconv.Itoa(movie_id)+"/similar", params, &resp); err != nil {
+		return Response{}, err
+	}
+	return resp, nil
+}
+
+// The window of release dates, as "YYYY-MM-DD", of movies in theaters
+type DateWindow struct {
+	Minimum string
+	Maximum string
+}
+
+// A page of the movies in theaters, along with the window of release
+// dates it covers
+type NowPlayingResponse struct {
+	Response
+	Dates DateWindow
+}
+
+// Get the given page of the movies now in theaters in a region (ISO
+// 3166-1 code, e.g. "US"), or in all regions if empty
+func (tmdb *TMDb) NowPlayingMovies(page int, region string) (NowPlayingResponse, error) {
+	var resp NowPlayingResponse
+	params := url.Values{}
+	set_page(params, page)
+	if region != "" {
+		params.Set("region", region)
+	}
+	if err := tmdb.get(context.Background(), "/movie/now_playing", params, &resp); err != nil {
+		return NowPlayingResponse{}, err
+	}
+	return resp, nil
+}
+
+// the pages of similar movies fetched by default, the later ones being
+// less and less similar
+const similar_pages = 5
+
+// Get the movies similar to a movie out of up to page_limit pages (0 for
+// the default of 5), without duplicates, the most popular first
+func (tmdb *TMDb) AllSimilarMovies(movie_id int, page_limit int) ([]Result, error) {
+	if page_limit <= 0 {
+		page_limit = similar_pages
+	}
+	results, err := AllPages(func(page int) (Response, error) {
+		resp, err := tmdb.MovieSimilar(movie_id, page)
+		if resp.Total_pages > page_limit {
+			resp.Total_pages = page_limit
+		}
+		return resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[ID]bool)
+	var similar []Result
+	for _, r := range results {
+		if !seen[r.Id] {
+			seen[r.Id] = true
+			similar = append(similar, r)
+		}
+	}
+	sort.Stable(byPopularity(similar))
+	return similar, nil
+}
+
+// a recommended movie and how it scores for a set of seed movies
+type recommendation struct {
+	result Result
+	// how many seeds recommend it
+	count int
+}
+
+// Recommend up to top_n movies for a set of seed movies (e.g. "because you
+// watched X and Y"), out of the first page of recommendations of each
+// seed. Movies recommended for more seeds rank first, then the better
+// rated ones. The seeds themselves are never recommended. Seeds are
+// fetched concurrently, within the rate limit, and the first error aborts
+func (tmdb *TMDb) RecommendationsForSet(movie_ids []int, top_n int) ([]Result, error) {
+	pages := make([]Response, len(movie_ids))
+	errs := make([]error, len(movie_ids))
+	var wg sync.WaitGroup
+	for i := range movie_ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pages[i], errs[i] = tmdb.MovieRecommendations(movie_ids[i], 1)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	seeds := make(map[ID]bool)
+	for _, id := range movie_ids {
+		seeds[ID(id)] = true
+	}
+	var recs []*recommendation
+	by_id := make(map[ID]*recommendation)
+	for _, page := range pages {
+		for _, r := range page.Results {
+			if seeds[r.Id] {
+				continue
+			}
+			if rec, ok := by_id[r.Id]; ok {
+				rec.count++
+				continue
+			}
+			rec := &recommendation{result: r, count: 1}
+			by_id[r.Id] = rec
+			recs = append(recs, rec)
+		}
+	}
+	sort.SliceStable(recs, func(i, j int) bool {
+		if recs[i].count != recs[j].count {
+			return recs[i].count > recs[j].count
+		}
+		if recs[i].result.Vote_average != recs[j].result.Vote_average {
+			return recs[i].result.Vote_average > recs[j].result.Vote_average
+		}
+		return recs[i].result.Id < recs[j].result.Id
+	})
+	if top_n >= 0 && len(recs) > top_n {
+		recs = recs[:top_n]
+	}
+	results := make([]Result, len(recs))
+	for i := range recs {
+		results[i] = recs[i].result
+	}
+	return results, nil
+}
+
+// All the data for the detail page of a movie
+type MoviePageData struct {
+	// the details of the movie, with its Credits
+	Movie           MovieMetadata
+	Videos          Videos
+	Recommendations []Result
+}
+
+// Get the details, credits, videos and first page of recommendations of a
+// movie in one call. They are fetched concurrently, within the rate
+// limit. In strict mode the first error aborts, otherwise only failing to
+// get the details does, and the sections that failed are left empty and
+// reported in a *PartialError
+func (tmdb *TMDb) MoviePage(movie_id int) (MoviePageData, error) {
+	ctx := context.Background()
+	id := strconv.Itoa(movie_id)
+	var page MoviePageData
+	var credits Credits
+	var recommendations Response
+	var details_err, credits_err, videos_err, recommendations_err error
+	var wg sync.WaitGroup
+	fetch := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+	fetch(func() { page.Movie, details_err = tmdb.getMovieDetails(ctx, id, false) })
+	fetch(func() { credits, credits_err = tmdb.getMovieCredits(ctx, id) })
+	fetch(func() { page.Videos, videos_err = tmdb.MovieVideos(movie_id) })
+	fetch(func() { recommendations, recommendations_err = tmdb.MovieRecommendations(movie_id, 1) })
+	wg.Wait()
+
+	if details_err != nil {
+		return MoviePageData{}, details_err
+	}
+	sections := sectionErrors{lenient: tmdb.lenient}
+	if !sections.ok("credits", credits_err) {
+		return MoviePageData{}, credits_err
+	}
+	if !sections.ok("videos", videos_err) {
+		return MoviePageData{}, videos_err
+	}
+	if !sections.ok("recommendations", recommendations_err) {
+		return MoviePageData{}, recommendations_err
+	}
+	page.Movie.Credits = credits
+	page.Movie.Media_type = "movie"
+	page.Recommendations = recommendations.Results
+	return page, sections.err()
+}
+
+// A list of movies and tv shows made by a TMDb user, with its items
+type List struct {
+	Id          ID
+	Name        string
+	Description string
+	Created_by  string
+	Item_count  int
+	Iso_639_1   string
+	Poster_path string
+	// tagged with their Media_type
+	Items       []Result
+	Total_pages int
+}
+
+// Get a public list of movies and tv shows made by a TMDb user, e.g. to
+// import it, with all its items out of all its pages
+func (tmdb *TMDb) ListData(list_id int) (List, error) {
+	var list List
+	for page := 1; page <= max_pages; page++ {
+		var resp List
+		params := url.Values{}
+		set_page(params, page)
+		if err := tmdb.get(context.Background(), "/list/"+strconv.Itoa(list_id), params, &resp); err != nil {
+			return List{}, err
+		}
+		items := append(list.Items, resp.Items...)
+		list = resp
+		list.Items = items
+		if page >= resp.Total_pages {
+			break
+		}
+	}
+	return list, nil
+}
+
+// The page of a movie, tv show or person on the TMDb website, as
+// media_type is "movie", "tv" or "person", e.g. for "View on TMDb" links
+func WebURL(media_type string, id int) (string, error) {
+	switch media_type {
+	case "movie", "tv", "person":
+		return "https://www.themoviedb.org/" + media_type + "/" + strconv.Itoa(id), nil
+	}
+	return "", errors.New("Unknown media type " + media_type + ", must be movie, tv or person")
+}
+
+// The external links of a movie, empty when it has none
+type Links struct {
+	Imdb_id  string
+	Homepage string
+	// the page of the movie on the TMDb website
+	Tmdb_url string
+}
+
+// Get the IMDb id, homepage and TMDb page of a movie, without the rest of
+// its metadata
+func (tmdb *TMDb) MovieLinks(movie_id int) (Links, error) {
+	var links Links
+	if err := tmdb.get(context.Background(), "/movie/"+strconv.Itoa(movie_id), nil, &links); err != nil {
+		return Links{}, err
+	}
+	links.Tmdb_url, _ = WebURL("movie", movie_id)
+	return links, nil
+}
+
+// A public list of movies made by a TMDb user
+type ListSummary struct {
+	Id             ID
+	Name           string
+	Description    string
+	Item_count     int
+	Favorite_count int
+	Iso_639_1      string
+	Poster_path    string
+}
+
+// A page of lists
+type ListsResponse struct {
+	Page          int
+	Results       []ListSummary
+	Total_pages   int
+	Total_results int
+}
+
+// Get the given page of the public lists a movie appears on
+func (tmdb *TMDb) MovieLists(movie_id int, page int) (ListsResponse, error) {
+	var resp ListsResponse
+	params := url.Values{}
+	set_page(params, page)
+	if err := tmdb.get(context.Background(), "/movie/"+strconv.Itoa(movie_id)+"/lists", params, &resp); err != nil {
+		return ListsResponse{}, err
+	}
+	return resp, nil
+}
+
+// The changes made on TMDb to a key of a movie (e.g. "poster_path")
+type Change struct {
+	Key   string
+	Items []ChangeItem
+}
+
+// A change to a key, the values are JSON of a shape depending on the key
+type ChangeItem struct {
+	Id             string
+	Action         string
+	Time           string
+	Iso_639_1      string
+	Value          json.RawMessage
+	Original_value json.RawMessage
+}
+
+// Get the changes made to a movie in the last 24 hours, only those to the
+// given keys if any are given. The keys TMDb knows of are in the
+// Change_keys of the configuration
+func (tmdb *TMDb) MovieChanges(movie_id int, keys ...string) ([]Change, error) {
+	var resp struct {
+		Changes []Change
+	}
+	if err := tmdb.get(context.Background(), "/movie/"+strconv.Itoa(movie_id)+"/changes", nil, &resp); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return resp.Changes, nil
+	}
+	var changes []Change
+	for _, change := range resp.Changes {
+		for _, key := range keys {
+			if change.Key == key {
+				changes = append(changes, change)
+				break
+			}
+		}
+	}
+	return changes, nil
+}
+
+// The title, overview and tagline of a movie in a language, any of them
+// may be blank when not translated
+type Translation struct {
+	Iso_3166_1   string
+	Iso_639_1    string
+	Name         string
+	English_name string
+	Data         TranslationData
+}
+
+type TranslationData struct {
+	Title    string
+	Overview string
+	Tagline  string
+	Homepage string
+}
+
+// All the translations of a movie
+type Translations []Translation
+
+// The translation into the language (ISO 639-1, e.g. "de"), false if there
+// is none. Out of several countries for the language, the most complete
+// translation is picked
+func (translations Translations) Language(lang string) (TranslationData, bool) {
+	var best TranslationData
+	found := false
+	for _, t := range translations {
+		if t.Iso_639_1 != lang {
+			continue
+		}
+		if !found || completeness(t.Data) > completeness(best) {
+			best, found = t.Data, true
+		}
+	}
+	return best, found
+}
+
+// how many of the texts of a translation are filled in
+func completeness(data TranslationData) int {
+	n := 0
+	for _, s := range []string{data.Title, data.Overview, data.Tagline} {
+		if s != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// Get the translations of a movie into all the languages available
+func (tmdb *TMDb) MovieTranslations(movie_id int) (Translations, error) {
+	var resp struct {
+		Translations Translations
+	}
+	if err := tmdb.get(context.Background(), "/movie/"+strconv.Itoa(movie_id)+"/translations", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Translations, nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAllSimilarMoviesPageLimit(t *testing.T) {
+	var requests int32
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		page := r.URL.Query().Get("page")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"page": %s, "total_pages": 500, "total_results": 10000, "results": [{"id": %s, "popularity": %s}]}`, page, page, page)
+	})
+	defer done()
+
+	for _, test := range []struct{ limit, pages int }{{0, similar_pages}, {2, 2}} {
+		atomic.StoreInt32(&requests, 0)
+		similar, err := db.AllSimilarMovies(1, test.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := int(atomic.LoadInt32(&requests)); n != test.pages || len(similar) != test.pages {
+			t.Errorf("limit %d: got %d requests and %d movies, want %d", test.limit, n, len(similar), test.pages)
+		}
+		if len(similar) > 1 && similar[0].Popularity < similar[1].Popularity {
+			t.Errorf("limit %d: not the most popular first", test.limit)
+		}
+	}
+}
+
+func TestAppendRelatedOnlyForMetadata(t *testing.T) {
+	var appends []string
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/3/movie/1" {
+			appends = append(appends, r.URL.Query().Get("append_to_response"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 1}`))
+	})
+	defer done()
+	db.SetAppendRelated(true)
+
+	if _, err := db.FetchPoster(1, ""); err != ErrNoPoster {
+		t.Fatalf("got %v, want ErrNoPoster", err)
+	}
+	if len(appends) != 1 || appends[0] != "" {
+		t.Errorf("got appends %q, want none for a poster", appends)
+	}
+}
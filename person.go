@@ -0,0 +1,23 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"strconv"
+)
+
+// Get the profile pictures of a person, empty if there are none
+func (tmdb *TMDb) PersonImages(person_id int) ([]Image, error) {
+	return tmdb.person_images(context.Background(), person_id)
+}
+
+func (tmdb *TMDb) person_images(ctx context.Context, person_id int) ([]Image, error) {
+	var images Images
+	if err := tmdb.get(ctx, "/person/"+strconv.Itoa(person_id)+"/images", nil, &images); err != nil {
+		return nil, err
+	}
+	return images.Profiles, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// A region (country) TMDb has watch provider data for
+type Region struct {
+	Iso_3166_1   string
+	English_name string
+	Native_name  string
+}
+
+// Get the regions TMDb has watch provider data for. They are fetched
+// once and cached, like the certifications
+func (tmdb *TMDb) WatchProviderRegions() ([]Region, error) {
+	ctx := context.Background()
+	tmdb.cache_mu.Lock()
+	cached := tmdb.regions
+	tmdb.cache_mu.Unlock()
+	if cached != nil && tmdb.use_cached(ctx) {
+		return append([]Region(nil), cached...), nil
+	}
+	var resp struct {
+		Results []Region
+	}
+	if err := tmdb.get(ctx, "/watch/providers/regions", nil, &resp); err != nil {
+		return nil, err
+	}
+	tmdb.cache_mu.Lock()
+	tmdb.regions = resp.Results
+	tmdb.cache_mu.Unlock()
+	// copies, so callers may sort them without changing the cached ones
+	return append([]Region(nil), resp.Results...), nil
+}
+
+// A streaming, rental or purchase service
+type Provider struct {
+	Provider_id      int
+	Provider_name    string
+	Logo_path        string
+	Display_priority int
+}
+
+// Where a movie can be watched in a region, by kind of offer. Link is the
+// JustWatch page listing them, to deep link users to
+type RegionProviders struct {
+	Link     string
+	Flatrate []Provider
+	Rent     []Provider
+	Buy      []Provider
+	Free     []Provider
+	Ads      []Provider
+}
+
+// Get where the movie with the given TMDb id can be watched in a region
+// (ISO 3166-1 code, e.g. "US"). It is empty when TMDb has no data for
+// the region
+func (tmdb *TMDb) WatchProviders(movie_id int, region string) (RegionProviders, error) {
+	var resp struct {
+		Results map[string]RegionProviders
+	}
+	if err := tmdb.get(context.Background(), "/movie/"+strconv.Itoa(movie_id)+"/watch/providers", nil, &resp); err != nil {
+		return RegionProviders{}, err
+	}
+	return resp.Results[region], nil
+}
+
+// Get all the watch providers of movies available in a region (ISO
+// 3166-1 code, e.g. "US"), e.g. to filter by streaming service. They are
+// fetched once per region and cached
+func (tmdb *TMDb) WatchProvidersList(region string) ([]Provider, error) {
+	ctx := context.Background()
+	tmdb.cache_mu.Lock()
+	cached, ok := tmdb.providers[region]
+	tmdb.cache_mu.Unlock()
+	if ok && tmdb.use_cached(ctx) {
+		return append([]Provider(nil), cached...), nil
+	}
+	var resp struct {
+		Results []Provider
+	}
+	params := url.Values{"watch_region": {region}}
+	if err := tmdb.get(ctx, "/watch/providers/movie", params, &resp); err != nil {
+		return nil, err
+	}
+	tmdb.cache_mu.Lock()
+	if tmdb.providers == nil {
+		tmdb.providers = make(map[string][]Provider)
+	}
+	tmdb.providers[region] = resp.Results
+	tmdb.cache_mu.Unlock()
+	return append([]Provider(nil), resp.Results...), nil
+}
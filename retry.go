@@ -0,0 +1,96 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// by default retries are limited to this many at once, refilled at one per second
+const default_retry_budget = 10
+
+// Retry requests that failed because of rate limiting (429) or a server
+// error (5xx) up to the given number of times, backing off in between.
+// No retries are made by default
+func (tmdb *TMDb) SetRetries(retries int) {
+	tmdb.retries = retries
+}
+
+// Set the size of the retry budget shared by all requests of this TMDb.
+// Each retry takes one from the budget, which is refilled at one per
+// second, so during an outage the total retries stay bounded. Once the
+// budget is spent, requests fail without retrying. Defaults to 10
+func (tmdb *TMDb) SetRetryBudget(size int) {
+	tmdb.budget = new_retry_budget(size, tmdb.time())
+}
+
+// token bucket of the retries allowed
+type retryBudget struct {
+	mu     sync.Mutex
+	clock  clock
+	tokens float64
+	size   float64
+	last   time.Time
+}
+
+func new_retry_budget(size int, c clock) *retryBudget {
+	return &retryBudget{clock: c, tokens: float64(size), size: float64(size), last: c.Now()}
+}
+
+// take one retry out of the budget, false if it is spent. A nil budget,
+// of a TMDb not made with Init, does not limit the retries
+func (b *retryBudget) take() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	b.tokens += now.Sub(b.last).Seconds()
+	if b.tokens > b.size {
+		b.tokens = b.size
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// whether a request failing with the status is worth retrying
+func retryable(status int) bool {
+	return status == 429 || status >= 500
+}
+
+// how long to wait before the given retry (counting from 0), as asked by
+// TMDb in Retry-After or else doubling from half a second
+func backoff(attempt int, header http.Header) time.Duration {
+	if secs, err := strconv.Atoi(header.Get("Retry-After")); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return (500 * time.Millisecond) << uint(attempt)
+}
+
+// whether waiting for d still leaves time before the context expires
+func (tmdb *TMDb) fits_deadline(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || !tmdb.time().Now().Add(d).After(deadline)
+}
+
+// wait for the backoff on the clock of the TMDb unless the context is done
+// first
+func (tmdb *TMDb) sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-tmdb.time().After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryDeadline(t *testing.T) {
+	var requests int32
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			// the first retry is right away, the next would be after a second
+			w.Header().Set("Retry-After", "0")
+		}
+		w.WriteHeader(429)
+		w.Write([]byte(`{"status_code": 25, "status_message": "Too many requests"}`))
+	})
+	defer done()
+	db.SetRetries(5)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := db.MovieDataContext(ctx, "Alien")
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("returned after %v, the deadline could not be met", elapsed)
+	}
+	var retry_err *RetryError
+	if !errors.As(err, &retry_err) {
+		t.Fatalf("got %v, want a *RetryError", err)
+	}
+	if retry_err.Attempts() != 2 || retry_err.LastStatus() != 429 {
+		t.Errorf("got %d attempts with status %d, want 2 with 429", retry_err.Attempts(), retry_err.LastStatus())
+	}
+	// only the retry made took from the budget
+	db.budget.mu.Lock()
+	tokens := db.budget.tokens
+	db.budget.mu.Unlock()
+	if tokens < 8.5 || tokens > 9.5 {
+		t.Errorf("%.1f retries left in the budget, want 9", tokens)
+	}
+}
+
+func TestRetryTransportError(t *testing.T) {
+	var requests int32
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(503)
+			w.Write([]byte(`{"status_code": 11, "status_message": "Internal error"}`))
+			return
+		}
+		// drop the connection without answering
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	})
+	defer done()
+	db.SetRetries(1)
+	_, err := db.TVEpisode(1, 1, 1)
+	var retry_err *RetryError
+	if !errors.As(err, &retry_err) {
+		t.Fatalf("got %v, want a *RetryError", err)
+	}
+	if retry_err.Attempts() != 2 || retry_err.LastStatus() != 503 {
+		t.Errorf("got %d attempts with status %d, want 2 with 503", retry_err.Attempts(), retry_err.LastStatus())
+	}
+}
+
+func TestRetryWithoutBudget(t *testing.T) {
+	var requests int32
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(503)
+			w.Write([]byte(`{"status_code": 11, "status_message": "Internal error"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 1}`))
+	})
+	defer done()
+	// a TMDb not made with Init has no budget
+	var zero TMDb
+	zero.SetHTTPClient(db.client)
+	zero.SetRetries(1)
+
+	if _, err := zero.TVEpisode(1, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("made %d requests, want 2", n)
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Options for the public search methods
+type SearchOption func(*searchOptions)
+
+type searchOptions struct {
+	sort_by           string
+	original_language string
+}
+
+// Reorder the results of a search by release date, either
+// "release_date_desc" (newest first) or "release_date_asc" (oldest first).
+// Results with an empty or invalid date are always sorted last
+func SortBy(order string) SearchOption {
+	return func(o *searchOptions) {
+		o.sort_by = order
+	}
+}
+
+// Keep only the results originally in the given language (ISO 639-1
+// code, e.g. "ja"). Results are filtered after the search so the totals
+// still count all results
+func OriginalLanguage(lang string) SearchOption {
+	return func(o *searchOptions) {
+		o.original_language = lang
+	}
+}
+
+// Search on TMDb for Movies with a given name, returning the given page of results
+func (tmdb *TMDb) SearchMovie(media_name string, page int, opts ...SearchOption) (Response, error) {
+	resp, err := tmdb.searchMovie(context.Background(), media_name, page)
+	if err != nil {
+		return Response{}, err
+	}
+	return apply_search_options(resp, opts)
+}
+
+// Search on TMDb for Tv Shows with a given name, returning the given page of results
+func (tmdb *TMDb) SearchTV(media_name string, page int, opts ...SearchOption) (Response, error) {
+	resp, err := tmdb.searchTmdbTv(context.Background(), media_name, page)
+	if err != nil {
+		return Response{}, err
+	}
+	return apply_search_options(resp, opts)
+}
+
+// Search on TMDb for TV, persons and Movies with a given name, returning
+// the given page of results
+func (tmdb *TMDb) SearchMulti(media_name string, page int, opts ...SearchOption) (Response, error) {
+	resp, err := tmdb.searchTmdbMulti(context.Background(), media_name, page)
+	if err != nil {
+		return Response{}, err
+	}
+	return apply_search_options(resp, opts)
+}
+
+// Search on TMDb for companies (studios) with a given name, returning
+// the given page of results with their ids, e.g. for the WithCompanies
+// discover filter
+func (tmdb *TMDb) SearchCompany(query string, page int) (Response, error) {
+	return tmdb.search(context.Background(), "/search/company", query, page)
+}
+
+// Search on TMDb for collections (franchises) with a given name,
+// returning the given page of results with their ids, names and posters,
+// e.g. for CollectionData. No results is an empty page, not an error
+func (tmdb *TMDb) SearchCollection(query string, page int) (Response, error) {
+	return tmdb.search(context.Background(), "/search/collection", query, page)
+}
+
+// Search on TMDb for keywords, returning the given page of results with
+// their ids, e.g. for the WithKeywords discover filter
+func (tmdb *TMDb) SearchKeyword(query string, page int) (Response, error) {
+	return tmdb.search(context.Background(), "/search/keyword", query, page)
+}
+
+// Search on TMDb for Movies and Tv Shows (but not persons) with a given
+// name, returning the given page of both searches merged into one, ranked
+// by popularity. Results are tagged with their Media_type
+func (tmdb *TMDb) SearchMedia(media_name string, page int, opts ...SearchOption) (Response, error) {
+	ctx := context.Background()
+	movies, err := tmdb.searchMovie(ctx, media_name, page)
+	if err != nil {
+		return Response{}, err
+	}
+	tv, err := tmdb.searchTmdbTv(ctx, media_name, page)
+	if err != nil {
+		return Response{}, err
+	}
+	resp := Response{
+		Page:          movies.Page,
+		Total_pages:   movies.Total_pages,
+		Total_results: movies.Total_results + tv.Total_results,
+	}
+	if tv.Total_pages > resp.Total_pages {
+		resp.Total_pages = tv.Total_pages
+	}
+	seen := make(map[string]bool)
+	add := func(results []Result, media_type string) {
+		for _, r := range results {
+			r.Media_type = media_type
+			key := media_type + "/" + r.Id.String()
+			if !seen[key] {
+				seen[key] = true
+				resp.Results = append(resp.Results, r)
+			}
+		}
+	}
+	add(movies.Results, "movie")
+	add(tv.Results, "tv")
+	sort.Stable(byPopularity(resp.Results))
+	return apply_search_options(resp, opts)
+}
+
+// Tell whether the best match for a name is a "movie", a "tv" show or a
+// "person", e.g. to pick between MovieData and TVData. Returns
+// ErrNoResults when nothing matches
+func (tmdb *TMDb) MediaType(media_name string) (string, error) {
+	results, err := tmdb.searchTmdbMulti(context.Background(), media_name, 0)
+	if err != nil {
+		return "", err
+	}
+	if err := results.no_results(); err != nil {
+		return "", err
+	}
+	return results.Results[best_match(media_name, results.Results, tmdb.min_popularity)].Media_type, nil
+}
+
+func apply_search_options(resp Response, opts []SearchOption) (Response, error) {
+	var o searchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.original_language != "" {
+		var results []Result
+		for _, r := range resp.Results {
+			if r.Original_language == o.original_language {
+				results = append(results, r)
+			}
+		}
+		resp.Results = results
+	}
+	switch o.sort_by {
+	case "":
+	case "release_date_desc":
+		sort.Stable(byReleaseDate{resp.Results, true})
+	case "release_date_asc":
+		sort.Stable(byReleaseDate{resp.Results, false})
+	default:
+		return Response{}, errors.New("Unknown sort order " + o.sort_by)
+	}
+	return resp, nil
+}
+
+// the release date of a result, movies and tv shows use different fields
+func (r *Result) release_time() (time.Time, bool) {
+	date := r.Release_date
+	if date == "" {
+		date = r.First_air_date
+	}
+	t, err := parse_date(date)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// sorts results by release date, the ones without a valid date go last.
+// Ties are broken by TMDb id so that the same results always come out in
+// the same order
+type byReleaseDate struct {
+	results []Result
+	desc    bool
+}
+
+func (s byReleaseDate) Len() int      { return len(s.results) }
+func (s byReleaseDate) Swap(i, j int) { s.results[i], s.results[j] = s.results[j], s.results[i] }
+func (s byReleaseDate) Less(i, j int) bool {
+	ti, iok := s.results[i].release_time()
+	tj, jok := s.results[j].release_time()
+	if iok != jok {
+		return iok
+	}
+	if iok && !ti.Equal(tj) {
+		if s.desc {
+			return ti.After(tj)
+		}
+		return ti.Before(tj)
+	}
+	return s.results[i].Id < s.results[j].Id
+}
+
+// TMDb does not serve pages beyond this one
+const max_pages = 500
+
+// Fetch all the pages of a paginated endpoint, starting with the first,
+// and return all their results. Stops at the total pages reported by TMDb
+// (capped to the 500 TMDb serves) and on the first error. For example
+//
+//	results, err := tmdb.AllPages(func(page int) (Response, error) {
+//		return db.SearchMovie("Alien", page)
+//	})
+func AllPages(fetch func(page int) (Response, error)) ([]Result, error) {
+	return AllPagesContext(context.Background(), func(_ context.Context, page int) (Response, error) {
+		return fetch(page)
+	})
+}
+
+// AllPages within a context, which is handed to fetch for each page and
+// stops the fetching once done. For example, in the background
+//
+//	ctx := tmdb.LowPriority(context.Background())
+//	results, err := tmdb.AllPagesContext(ctx, func(ctx context.Context, page int) (Response, error) {
+//		return db.DiscoverMoviesContext(ctx, opts, page)
+//	})
+func AllPagesContext(ctx context.Context, fetch func(ctx context.Context, page int) (Response, error)) ([]Result, error) {
+	var results []Result
+	for page := 1; page <= max_pages; page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		resp, err := fetch(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, resp.Results...)
+		if page >= resp.Total_pages {
+			break
+		}
+	}
+	return results, nil
+}
+
+// The results of a find, grouped per media type
+type FindResponse struct {
+	Movie_results      []Result
+	Person_results     []Result
+	Tv_results         []Result
+	Tv_episode_results []Result
+	Tv_season_results  []Result
+}
+
+// external sources that can be used to look up TMDb entries
+var external_sources = []string{"imdb_id", "tvdb_id", "facebook_id", "instagram_id", "twitter_id"}
+
+// Find movies, tv shows, persons, seasons and episodes on TMDb by an id
+// from an external source, one of imdb_id, tvdb_id, facebook_id,
+// instagram_id or twitter_id
+func (tmdb *TMDb) FindByExternalID(id, source string) (FindResponse, error) {
+	var resp FindResponse
+	valid := false
+	for i := range external_sources {
+		if external_sources[i] == source {
+			valid = true
+		}
+	}
+	if !valid {
+		return resp, errors.New("Unknown external source " + source + ", must be one of " + strings.Join(external_sources, ", "))
+	}
+	params := url.Values{"external_source": {source}}
+	if err := tmdb.get(context.Background(), "/find/"+url.PathEscape(id), params, &resp); err != nil {
+		return FindResponse{}, err
+	}
+	return resp, nil
+}
+
+// sorts results by popularity, the most popular first, with ties broken by TMDb id
+type byPopularity []Result
+
+func (s byPopularity) Len() int      { return len(s) }
+func (s byPopularity) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byPopularity) Less(i, j int) bool {
+	if s[i].Popularity != s[j].Popularity {
+		return s[i].Popularity > s[j].Popularity
+	}
+	return s[i].Id < s[j].Id
+}
+
+// A lightweight projection of a result with only its id, title and year
+// fields, to hold many of them (e.g. when scanning a large library)
+type MovieLite struct {
+	Id           ID
+	Title        string
+	Release_date string
+	Poster_path  string
+}
+
+// The lightweight projection of a movie or tv show result, using the name
+// and first air date of tv shows
+func (r *Result) Lite() MovieLite {
+	lite := MovieLite{Id: r.Id, Title: r.Title, Release_date: r.Release_date, Poster_path: r.Poster_path}
+	if lite.Title == "" {
+		lite.Title = r.Name
+	}
+	if lite.Release_date == "" {
+		lite.Release_date = r.First_air_date
+	}
+	return lite
+}
+
+// The lightweight projections of results, e.g. of AllPages, so the full
+// results can be released
+func LiteResults(results []Result) []MovieLite {
+	lite := make([]MovieLite, len(results))
+	for i := range results {
+		lite[i] = results[i].Lite()
+	}
+	return lite
+}
+
+// Write results (e.g. of AllPages) to w as a JSON array, one result at a
+// time, so large sets are not encoded all at once
+func WriteResultsJSON(w io.Writer, results []Result) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	for i := range results {
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(&results[i]); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "]\n")
+	return err
+}
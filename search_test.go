@@ -0,0 +1,40 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFindByExternalIDEscape(t *testing.T) {
+	var path string
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"movie_results": []}`))
+	})
+	defer done()
+
+	if _, err := db.FindByExternalID("some user", "twitter_id"); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/3/find/some user" {
+		t.Errorf("got path %q, want /3/find/some user", path)
+	}
+}
+
+func TestWriteResultsJSONKeys(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteResultsJSON(&buf, []Result{{Id: 1, Release_date: "1999-03-31"}}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `"release_date":"1999-03-31"`) || strings.Contains(out, "Release_date") {
+		t.Errorf("got %s, want snake_case keys", out)
+	}
+}
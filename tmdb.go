@@ -27,29 +27,107 @@
 //		}
 //	}
 //
-// the metadata is returned in XML format according to TMDb guidelines.
+// the metadata is returned in JSON format according to TMDb guidelines.
+//
+// The JSON output is deterministic, the same data always marshals to the
+// same bytes: struct fields come out in declaration order and map keys are
+// sorted by encoding/json, so it can be diffed or hashed across runs.
 //
 package tmdb
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 const base_url string = "http://api.themoviedb.org/3"
 
+// Returned when the name to search for is empty (or only whitespace)
+var ErrEmptyQuery = errors.New("Empty query for TMDb search")
+
+// Returned when a search finds nothing
+var ErrNoResults = errors.New("No results found at TMDb")
+
+// Returned when the requested resource (e.g. a movie id) does not exist at TMDb
+var ErrNotFound = errors.New("The resource requested could not be found at TMDb")
+
+// Returned when TMDb does not answer with JSON, usually because it is down
+// for maintenance
+var ErrServiceUnavailable = errors.New("TMDb is unavailable (not responding with JSON)")
+
+// Returned when image URLs are needed but the configuration is not
+// available, as it is not fetched after SetFetchConfig(false)
+var ErrNoConfig = errors.New("No TMDb configuration to build image URLs with")
+
 type TMDb struct {
 	api_key string
-	config  *tmdbConfig
+	// fetched once, guarded by config_mu so concurrent callers share a fetch
+	config_mu sync.Mutex
+	config    *Config
+	// reference data cached on demand, guarded by cache_mu
+	cache_mu       sync.Mutex
+	regions        []Region
+	certifications map[string][]Certification
+	providers      map[string][]Provider
+	movie_genres   []Genre
+	tv_genres      []Genre
+
+	sizes    ImageSizePrefs
+	client   *http.Client
+	retries  int
+	budget   *retryBudget
+	timeouts Timeouts
+	limiter  *rateLimiter
+	cache    Cache
+	// retry a movie search without results with a multi search
+	multi_fallback bool
+	// language of the texts, TMDb's default (English) if empty, and the
+	// one blank texts are filled in from
+	language          string
+	fallback_language string
+	// layout of the date in the simplified output, the year if empty
+	date_layout string
+	// assemble metadata with failed sections left empty
+	lenient bool
+	// region whose release date is used by ToJSON
+	release_region string
+	// top billed cast whose best voted profile picture is used
+	best_photos int
+	// recommendations and similar movies are appended to the details
+	append_related bool
+	// cached responses are not used
+	refresh bool
+	// query parameters sent with all requests
+	extra_params url.Values
+	// the source of time, the wall clock unless replaced in tests
+	clock clock
+	// departments the crew is limited to, all if empty
+	crew_departments []string
+	// the configuration is not fetched, e.g. by text only consumers
+	no_config bool
+	// results less popular are not matched when others are
+	min_popularity float64
+	// ids of movies by title, as corrected by the user
+	matches_mu sync.RWMutex
+	matches    map[string]ID
+	// maximum cast and crew kept in the metadata, 0 for all
+	cast_limit int
+	crew_limit int
 }
 
 func Init(api_key string) *TMDb {
-	return &TMDb{api_key: api_key}
+	return &TMDb{api_key: api_key, budget: new_retry_budget(default_retry_budget, realClock{})}
 }
 
 type filtered_output struct {
@@ -58,302 +136,580 @@ type filtered_output struct {
 	Release_date string `json:"year"`
 }
 
-// response of search/multi
-type tmdbResponse struct {
-	Page          int
-	Results       []tmdbResult
-	Total_pages   int
-	Total_results int
+// A page of results of a search, or of any other paginated list
+type Response struct {
+	Page          int      `json:"page"`
+	Results       []Result `json:"results"`
+	Total_pages   int      `json:"total_pages"`
+	Total_results int      `json:"total_results"`
+}
+
+// ErrNoResults if the search found nothing, that is when both the total
+// and the results are empty. The total alone is not trusted, it may be
+// missing (zero) when the response changes shape, while there are results
+func (resp *Response) no_results() error {
+	if len(resp.Results) > 0 {
+		return nil
+	}
+	if resp.Total_results == 0 {
+		return ErrNoResults
+	}
+	return errors.New(fmt.Sprintf("TMDb reported %d results but returned none", resp.Total_results))
 }
 
-// results format from Tmdb
-type tmdbResult struct {
-	Adult          bool
-	Name           string
-	Backdrop_path  string
-	Id             int
-	Original_name  string
-	Original_title string
-	First_air_date string
-	Release_date   string
-	Poster_path    string
-	Title          string
-	Media_type     string
-	Profile_path   string
+// A result (a movie, tv show or person) from TMDb
+type Result struct {
+	Adult          bool   `json:"adult"`
+	Name           string `json:"name"`
+	Backdrop_path  string `json:"backdrop_path"`
+	Id             ID     `json:"id"`
+	Original_name  string `json:"original_name"`
+	Original_title string `json:"original_title"`
+	// ISO 639-1 code, e.g. "ja"
+	Original_language string `json:"original_language"`
+	First_air_date    string `json:"first_air_date"`
+	Release_date      string `json:"release_date"`
+	Poster_path       string `json:"poster_path"`
+	Title             string `json:"title"`
+	Media_type        string `json:"media_type"`
+	Profile_path      string `json:"profile_path"`
+	// for companies
+	Logo_path      string `json:"logo_path"`
+	Origin_country string `json:"origin_country"`
+	// see GenreNames
+	Genre_ids []int `json:"genre_ids"`
+	// whether TMDb has videos (e.g. trailers) for this movie
+	Video        bool    `json:"video"`
+	Popularity   float64 `json:"popularity"`
+	Vote_average float64 `json:"vote_average"`
+	Vote_count   int     `json:"vote_count"`
 }
 
-// response of config
-type tmdbConfig struct {
-	Images imageConfig
+// The configuration of TMDb
+type Config struct {
+	Images ImageConfig `json:"images"`
+	// the keys changes are reported with, e.g. "poster_path"
+	Change_keys []string `json:"change_keys"`
+	// set when TMDb could not be reached for its configuration and the
+	// well-known defaults were used instead
+	Fallback bool `json:"fallback,omitempty"`
 }
 
-// Image configurtion
-type imageConfig struct {
-	Base_url        string
-	Secure_base_url string
+// Image configuration, with the base URLs and available sizes to build image URLs
+type ImageConfig struct {
+	Base_url        string `json:"base_url"`
+	Secure_base_url string `json:"secure_base_url"`
 
 	//possible sizes for images
-	Backdrop_sizes []string
-	Logo_sizes     []string
-	Poster_sizes   []string
-	Profile_sizes  []string
-	Still_sizes    []string
+	Backdrop_sizes []string `json:"backdrop_sizes"`
+	Logo_sizes     []string `json:"logo_sizes"`
+	Poster_sizes   []string `json:"poster_sizes"`
+	Profile_sizes  []string `json:"profile_sizes"`
+	Still_sizes    []string `json:"still_sizes"`
 }
 
-// Movie metadata structure
-type movieMetadata struct {
-	Id            int
-	Media_type    string
-	Backdrop_path string
-	Poster_path   string
-	Credits       tmdbCredits
-	Config        *tmdbConfig
-	Imdb_id       string
-	Overview      string
-	Title         string
-	Release_date  string
+// The version of the JSON metadata returned by MovieData and TVData, in
+// its schema_version field. It is raised when the shape of the metadata
+// changes, so stored metadata can be told apart
+const SchemaVersion = 1
+
+// Movie metadata structure, as returned in JSON by MovieData
+type MovieMetadata struct {
+	Id            ID      `json:"id"`
+	Media_type    string  `json:"media_type"`
+	Backdrop_path string  `json:"backdrop_path"`
+	Poster_path   string  `json:"poster_path"`
+	Credits       Credits `json:"credits"`
+	Config        *Config `json:"config"`
+	Imdb_id       string  `json:"imdb_id"`
+	Overview      string  `json:"overview"`
+	Title         string  `json:"title"`
+	Release_date  string  `json:"release_date"`
+	Vote_average  float64 `json:"vote_average"`
+	Genres        []Genre `json:"genres"`
+	// the collection (franchise) the movie is part of, nil if none
+	Belongs_to_collection *CollectionSummary `json:"belongs_to_collection"`
+	// the first page of recommended and similar movies, only fetched
+	// after SetAppendRelated(true)
+	Recommendations *Response `json:"recommendations,omitempty"`
+	Similar         *Response `json:"similar,omitempty"`
+	// SchemaVersion when fetched
+	Schema_version int `json:"schema_version"`
 }
 
-type tmdbCredits struct {
-	Id   int
-	Cast []tmdbCast
-	Crew []tmdbCrew
+// A collection of movies (e.g. a franchise) as referenced from its movies
+type CollectionSummary struct {
+	Id            ID     `json:"id"`
+	Name          string `json:"name"`
+	Poster_path   string `json:"poster_path"`
+	Backdrop_path string `json:"backdrop_path"`
 }
 
-type tmdbCast struct {
-	Character    string
-	Name         string
-	Profile_path string
+// The cast and crew of a movie or tv show
+type Credits struct {
+	Id   ID     `json:"id"`
+	Cast []Cast `json:"cast"`
+	Crew []Crew `json:"crew"`
 }
 
-type tmdbCrew struct {
-	Department   string
-	Name         string
-	Job          string
-	Profile_path string
+// A cast member, i.e. an actor and the character played
+type Cast struct {
+	// the TMDb id of the person
+	Id           ID     `json:"id"`
+	Character    string `json:"character"`
+	Name         string `json:"name"`
+	Profile_path string `json:"profile_path"`
+}
+
+// A crew member and the job done
+type Crew struct {
+	Department   string `json:"department"`
+	Name         string `json:"name"`
+	Job          string `json:"job"`
+	Profile_path string `json:"profile_path"`
 }
 
 // The main call for getting movie data media_name is the (plain) name of
 // the movie information to be retrieved without year or other information
+//
+// The result with a title matching the name (ignoring case, accents and
+// punctuation) is preferred over TMDb's top hit
+//
+// The metadata is returned as JSON with snake_case keys, following TMDb's
+// own naming (e.g. "release_date", "poster_path")
 func (tmdb *TMDb) MovieData(media_name string) (string, error) {
-	results, err := tmdb.searchMovie(media_name)
+	return tmdb.MovieDataContext(context.Background(), media_name)
+}
+
+// Get the texts (titles, overviews, ...) in the given language (ISO 639-1,
+// optionally with the country, e.g. "de" or "pt-BR") instead of English
+func (tmdb *TMDb) SetLanguage(language string) {
+	tmdb.language = language
+}
+
+// When the title or overview of a movie is blank in the language set, as
+// is common for lesser known movies, MovieData fills them in from the
+// given language, usually "en". Empty (the default) to leave them blank
+func (tmdb *TMDb) SetFallbackLanguage(language string) {
+	tmdb.fallback_language = language
+}
+
+// When enabled, MovieData retries a movie search that found nothing with
+// a search across movies, tv shows and persons, and uses its top hit if it
+// is a movie
+func (tmdb *TMDb) SetMultiSearchFallback(enabled bool) {
+	tmdb.multi_fallback = enabled
+}
+
+// Like MovieData, with the requests made within the context, giving up
+// when it is done
+func (tmdb *TMDb) MovieDataContext(ctx context.Context, media_name string) (string, error) {
+	id, err := tmdb.match_movie(ctx, media_name)
 	if err != nil {
 		return "", err
 	}
-	if results.Total_results == 0 {
-		return "", errors.New("No results found at TMDb")
+	return tmdb.movie_json(ctx, id)
+}
+
+// Write the data of a movie like MovieData does straight to w, e.g. the
+// response of an HTTP handler, without holding it all in a string
+func (tmdb *TMDb) WriteMovieDataJSON(w io.Writer, media_name string) error {
+	ctx := context.Background()
+	id, err := tmdb.match_movie(ctx, media_name)
+	if err != nil {
+		return err
 	}
-	if results.Results[0].Media_type == "person" {
-		return "", errors.New("Metadata for persons not supported")
+	movie_details, err := tmdb.movie_metadata(ctx, id)
+	if _, partial := err.(*PartialError); err != nil && !partial {
+		return err
 	}
-	if results.Results[0].Media_type == "tv" {
-		return "", errors.New("Metadata for tv not supported inside a call for movie data")
+	if eerr := json.NewEncoder(w).Encode(movie_details); eerr != nil {
+		return eerr
 	}
+	return err
+}
 
-	// otherwise
-	movie_details, err := tmdb.getMovieDetails(strconv.Itoa(results.Results[0].Id))
-	if err != nil {
-		return "", err
+// the TMDb id of the movie matching the name, the remembered one if any
+func (tmdb *TMDb) match_movie(ctx context.Context, media_name string) (ID, error) {
+	if id, ok := tmdb.remembered(media_name); ok {
+		return id, nil
 	}
-	movie_details.Credits, err = tmdb.getMovieCredits(strconv.Itoa(results.Results[0].Id))
+	results, err := tmdb.searchMovie(ctx, media_name, 0)
 	if err != nil {
-		return "", err
+		return 0, err
 	}
-	movie_details.Config, err = tmdb.getConfig()
-	if err != nil {
-		return "", err
+	if len(results.Results) == 0 && tmdb.multi_fallback {
+		results, err = tmdb.searchTmdbMulti(ctx, media_name, 0)
+		if err != nil {
+			return 0, err
+		}
 	}
-	movie_details.Id = results.Results[0].Id
-	movie_details.Media_type = "movie"
-
-	metadata, err := json.Marshal(movie_details)
-	if err != nil {
-		return "", err
+	if err := results.no_results(); err != nil {
+		return 0, err
 	}
-	return string(metadata), nil
+	match := results.Results[best_match(media_name, results.Results, tmdb.min_popularity)]
+	if match.Media_type == "person" {
+		return 0, errors.New("Metadata for persons not supported")
+	}
+	if match.Media_type == "tv" {
+		return 0, errors.New("Metadata for tv not supported inside a call for movie data")
+	}
+
+	// otherwise
+	return match.Id, nil
 }
 
-// Search on TMDb for TV, persons and Movies with a given name
-func (tmdb *TMDb) searchTmdbMulti(media_name string) (tmdbResponse, error) {
-	var resp tmdbResponse
-	res, err := http.Get(base_url + "/search/multi?api_key=" + tmdb.api_key + "&query=" + url.QueryEscape(media_name))
-	if err != nil {
-		return resp, err
-	}
-	if res.StatusCode != 200 {
-		return resp, error_status(res.StatusCode)
+// Get the data of the movie with the given TMDb id, in the same JSON
+// format as MovieData, e.g. when the user picked the right movie
+func (tmdb *TMDb) MovieDataByID(movie_id int) (string, error) {
+	return tmdb.MovieDataByIDContext(context.Background(), movie_id)
+}
+
+// MovieDataByID within a context, e.g. a LowPriority one for background
+// refreshes
+func (tmdb *TMDb) MovieDataByIDContext(ctx context.Context, movie_id int) (string, error) {
+	return tmdb.movie_json(ctx, ID(movie_id))
+}
+
+// Get the data of many movies by their TMDb ids, like MovieDataByID, with
+// up to concurrency of them fetched at once, within the rate limit. The
+// data and the errors are keyed by id, movies with incomplete data (see
+// SetStrict) are in both
+func (tmdb *TMDb) MovieDataByIDs(ids []int, concurrency int) (map[int]string, map[int]error) {
+	return tmdb.MovieDataByIDsContext(context.Background(), ids, concurrency)
+}
+
+// MovieDataByIDs within a context, e.g. a LowPriority one so a sync job
+// does not hold up the requests of users
+func (tmdb *TMDb) MovieDataByIDsContext(ctx context.Context, ids []int, concurrency int) (map[int]string, map[int]error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	data := make(map[int]string)
+	errs := make(map[int]error)
+	var mu sync.Mutex
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				metadata, err := tmdb.MovieDataByIDContext(ctx, id)
+				mu.Lock()
+				if metadata != "" {
+					data[id] = metadata
+				}
+				if err != nil {
+					errs[id] = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, id := range ids {
+		jobs <- id
+	}
+	close(jobs)
+	wg.Wait()
+	return data, errs
+}
+
+// Remember the TMDb id of the movie with the given title, so that later
+// calls to MovieData for that exact title use it instead of searching,
+// e.g. to apply a user's correction of a wrong match
+func (tmdb *TMDb) RememberMatch(title string, movie_id int) {
+	tmdb.matches_mu.Lock()
+	defer tmdb.matches_mu.Unlock()
+	if tmdb.matches == nil {
+		tmdb.matches = make(map[string]ID)
 	}
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return tmdbResponse{}, err
+	tmdb.matches[title] = ID(movie_id)
+}
+
+// the remembered id for a title
+func (tmdb *TMDb) remembered(title string) (ID, bool) {
+	tmdb.matches_mu.RLock()
+	defer tmdb.matches_mu.RUnlock()
+	id, ok := tmdb.matches[title]
+	return id, ok
+}
+
+// the metadata of a movie as JSON, along with a *PartialError when incomplete
+func (tmdb *TMDb) movie_json(ctx context.Context, id ID) (string, error) {
+	movie_details, err := tmdb.movie_metadata(ctx, id)
+	if _, partial := err.(*PartialError); err != nil && !partial {
+		return "", err
 	}
-	if err := json.Unmarshal(body, &resp); err != nil {
-		return tmdbResponse{}, err
+	metadata, merr := json.Marshal(movie_details)
+	if merr != nil {
+		return "", merr
 	}
-	return resp, nil
+	return string(metadata), err
 }
 
-// Search on TMDb for Movies with a given name
-func (tmdb *TMDb) searchMovie(media_name string) (tmdbResponse, error) {
-	var resp tmdbResponse
-	res, err := http.Get(base_url + "/search/movie?api_key=" + tmdb.api_key + "&query=" + url.QueryEscape(media_name))
+// the full metadata of a movie, along with a *PartialError when incomplete
+func (tmdb *TMDb) movie_metadata(ctx context.Context, id ID) (MovieMetadata, error) {
+	movie_details, err := tmdb.getMovieDetails(ctx, id.String(), tmdb.append_related)
 	if err != nil {
-		return resp, err
+		return MovieMetadata{}, err
 	}
-	if res.StatusCode != 200 {
-		return resp, error_status(res.StatusCode)
-	}
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return tmdbResponse{}, err
+	sections := sectionErrors{lenient: tmdb.lenient}
+	if tmdb.fallback_language != "" && (movie_details.Title == "" || movie_details.Overview == "") {
+		fallback, err := tmdb.getMovieDetailsLanguage(ctx, id.String(), tmdb.fallback_language)
+		if !sections.ok("fallback language", err) {
+			return MovieMetadata{}, err
+		}
+		movie_details.fill_texts(fallback)
 	}
-	if err := json.Unmarshal(body, &resp); err != nil {
-		return tmdbResponse{}, err
+	movie_details.Credits, err = tmdb.getMovieCredits(ctx, id.String())
+	if !sections.ok("credits", err) {
+		return MovieMetadata{}, err
 	}
-	return resp, nil
+	tmdb.best_cast_photos(ctx, movie_details.Credits.Cast)
+	movie_details.Config = tmdb.metadata_config(ctx)
+	movie_details.Id = id
+	movie_details.Media_type = "movie"
+	movie_details.Schema_version = SchemaVersion
+	return movie_details, sections.err()
+}
+
+// Search on TMDb for TV, persons and Movies with a given name
+func (tmdb *TMDb) searchTmdbMulti(ctx context.Context, media_name string, page int) (Response, error) {
+	return tmdb.search(ctx, "/search/multi", media_name, page)
+}
+
+// Search on TMDb for Movies with a given name
+func (tmdb *TMDb) searchMovie(ctx context.Context, media_name string, page int) (Response, error) {
+	return tmdb.search(ctx, "/search/movie", media_name, page)
 }
 
 // Search on TMDb for Tv Shows with a given name
-func (tmdb *TMDb) searchTmdbTv(media_name string) (tmdbResponse, error) {
-	var resp tmdbResponse
-	res, err := http.Get(base_url + "/search/tv?api_key=" + tmdb.api_key + "&query=" + url.QueryEscape(media_name))
-	if err != nil {
-		return resp, err
-	}
-	if res.StatusCode != 200 {
-		return resp, error_status(res.StatusCode)
-	}
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return tmdbResponse{}, err
+func (tmdb *TMDb) searchTmdbTv(ctx context.Context, media_name string, page int) (Response, error) {
+	return tmdb.search(ctx, "/search/tv", media_name, page)
+}
+
+// Search on the given TMDb search endpoint
+func (tmdb *TMDb) search(ctx context.Context, path string, media_name string, page int) (Response, error) {
+	var resp Response
+	media_name = strings.TrimSpace(media_name)
+	if media_name == "" {
+		return resp, ErrEmptyQuery
 	}
-	if err := json.Unmarshal(body, &resp); err != nil {
-		return tmdbResponse{}, err
+	params := url.Values{"query": {media_name}}
+	set_page(params, page)
+	if err := tmdb.get(ctx, path, params, &resp); err != nil {
+		return Response{}, err
 	}
 	return resp, nil
 }
 
-// Get configurations from TMDb
-func (tmdb *TMDb) getConfig() (*tmdbConfig, error) {
-	if tmdb.config == nil || tmdb.config.Images.Base_url == "" {
-		var conf = &tmdbConfig{}
-		res, err := http.Get(base_url + "/configuration?api_key=" + tmdb.api_key)
-		if err != nil {
-			return conf, err
-		}
-		if res.StatusCode != 200 {
-			return conf, error_status(res.StatusCode)
+// Get configurations from TMDb, only the preloaded ones when fetching is off
+func (tmdb *TMDb) getConfig(ctx context.Context) (*Config, error) {
+	if tmdb.no_config {
+		tmdb.config_mu.Lock()
+		defer tmdb.config_mu.Unlock()
+		if tmdb.config == nil {
+			return &Config{}, ErrNoConfig
 		}
-		body, err := ioutil.ReadAll(res.Body)
-		if err != nil {
-			return &tmdbConfig{}, err
-		}
-		if err := json.Unmarshal(body, &conf); err != nil {
-			return &tmdbConfig{}, err
+		return tmdb.config, nil
+	}
+	return tmdb.fetch_config(ctx)
+}
+
+// Get configurations from TMDb unless already cached
+func (tmdb *TMDb) fetch_config(ctx context.Context) (*Config, error) {
+	tmdb.config_mu.Lock()
+	defer tmdb.config_mu.Unlock()
+	if tmdb.config == nil || tmdb.config.Images.Base_url == "" {
+		var conf = &Config{}
+		if err := tmdb.get(ctx, "/configuration", nil, conf); err != nil {
+			return &Config{}, err
 		}
 		tmdb.config = conf
 	}
 	return tmdb.config, nil
 }
 
-// Get basic information for movie
-func (tmdb *TMDb) getMovieDetails(MediaId string) (movieMetadata, error) {
-	var met movieMetadata
-	res, err := http.Get(base_url + "/movie/" + MediaId + "?api_key=" + tmdb.api_key)
+// Whether MovieData and TVData fetch the configuration of TMDb (the
+// default) for the image URLs. When not, saving a request, they use the
+// configuration preloaded with PreloadConfig. Without one their Config is
+// left nil, and ToJSON and the image helpers fail with ErrNoConfig
+func (tmdb *TMDb) SetFetchConfig(fetch bool) {
+	tmdb.no_config = !fetch
+}
+
+// the image base URL and sizes TMDb has been serving for years
+var fallback_config = Config{
+	Images: ImageConfig{
+		Base_url:        "https://image.tmdb.org/t/p/",
+		Secure_base_url: "https://image.tmdb.org/t/p/",
+		Backdrop_sizes:  []string{"w300", "w780", "w1280", "original"},
+		Logo_sizes:      []string{"w45", "w92", "w154", "w185", "w300", "w500", "original"},
+		Poster_sizes:    []string{"w92", "w154", "w185", "w342", "w500", "w780", "original"},
+		Profile_sizes:   []string{"w45", "w185", "h632", "original"},
+		Still_sizes:     []string{"w92", "w185", "w300", "original"},
+	},
+	Fallback: true,
+}
+
+// the configuration of TMDb, or the well-known defaults (flagged as
+// Fallback) when it cannot be fetched, so that metadata can still be
+// assembled during configuration outages. The defaults are not cached,
+// the next call tries again
+func (tmdb *TMDb) config_or_fallback(ctx context.Context) *Config {
+	config, err := tmdb.getConfig(ctx)
 	if err != nil {
-		return met, err
+		fallback := fallback_config
+		return &fallback
 	}
-	if res.StatusCode != 200 {
-		return met, error_status(res.StatusCode)
+	return config
+}
+
+// the configuration for metadata. When it is not fetched, the preloaded
+// one, nil if none
+func (tmdb *TMDb) metadata_config(ctx context.Context) *Config {
+	if tmdb.no_config {
+		tmdb.config_mu.Lock()
+		defer tmdb.config_mu.Unlock()
+		return tmdb.config
 	}
-	body, err := ioutil.ReadAll(res.Body)
+	return tmdb.config_or_fallback(ctx)
+}
+
+// Fetch the configuration of TMDb ahead of time, e.g. at startup, so that
+// the first MovieData does not wait for it. It is only fetched once, so
+// this is safe to call several times and concurrently. It fetches the
+// configuration even when SetFetchConfig(false) was called
+func (tmdb *TMDb) PreloadConfig(ctx context.Context) error {
+	_, err := tmdb.fetch_config(ctx)
+	return err
+}
+
+// The image configuration of TMDb, fetched if not already cached. Image
+// URLs are made of the base URL, one of the sizes of the kind of image and
+// the image path
+func (tmdb *TMDb) ImageConfig() (ImageConfig, error) {
+	config, err := tmdb.getConfig(context.Background())
 	if err != nil {
-		return movieMetadata{}, err
+		return ImageConfig{}, err
 	}
-	if err := json.Unmarshal(body, &met); err != nil {
-		return movieMetadata{}, err
-	}
-	return met, nil
+	return config.Images, nil
 }
 
-// Get credits for movie
-func (tmdb *TMDb) getMovieCredits(MediaId string) (tmdbCredits, error) {
-	var cred tmdbCredits
-	res, err := http.Get(base_url + "/movie/" + MediaId + "/credits?api_key=" + tmdb.api_key)
+// Get the movie most recently added to TMDb, its id being the highest
+// valid movie id. Only the basic details are filled in
+func (tmdb *TMDb) LatestMovie() (MovieMetadata, error) {
+	md, err := tmdb.getMovieDetails(context.Background(), "latest", false)
 	if err != nil {
-		return cred, err
+		return MovieMetadata{}, err
 	}
-	if res.StatusCode != 200 {
-		return cred, error_status(res.StatusCode)
+	md.Media_type = "movie"
+	return md, nil
+}
+
+// Get basic information for movie, with the first page of recommended and
+// similar movies in the same request when related
+func (tmdb *TMDb) getMovieDetails(ctx context.Context, MediaId string, related bool) (MovieMetadata, error) {
+	var met MovieMetadata
+	params := url.Values{}
+	if related {
+		if err := set_appends(params, "recommendations", "similar"); err != nil {
+			return MovieMetadata{}, err
+		}
 	}
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return tmdbCredits{}, err
+	if err := tmdb.get(ctx, "/movie/"+MediaId, params, &met); err != nil {
+		return MovieMetadata{}, err
 	}
-	if err := json.Unmarshal(body, &cred); err != nil {
-		return tmdbCredits{}, err
+	if related {
+		if met.Recommendations == nil {
+			met.Recommendations = &Response{}
+		}
+		if met.Similar == nil {
+			met.Similar = &Response{}
+		}
 	}
-	return cred, nil
+	return met, nil
 }
 
-// Get basic information for Tv
-func (tmdb *TMDb) getTmdbTvDetails(MediaId string) (movieMetadata, error) {
-	var met movieMetadata
-	res, err := http.Get(base_url + "/tv/" + MediaId + "?api_key=" + tmdb.api_key)
-	if err != nil {
-		return met, err
+// Fetch the first page of recommended and similar movies along with the
+// details of movies in MovieData, in the same request
+func (tmdb *TMDb) SetAppendRelated(append_related bool) {
+	tmdb.append_related = append_related
+}
+
+// Get basic information for movie in the given language
+func (tmdb *TMDb) getMovieDetailsLanguage(ctx context.Context, MediaId string, language string) (MovieMetadata, error) {
+	var met MovieMetadata
+	if err := tmdb.get(ctx, "/movie/"+MediaId, url.Values{"language": {language}}, &met); err != nil {
+		return MovieMetadata{}, err
+	}
+	return met, nil
+}
+
+// fill the blank texts of the metadata with the ones in another language
+func (md *MovieMetadata) fill_texts(other MovieMetadata) {
+	if md.Title == "" {
+		md.Title = other.Title
 	}
-	if res.StatusCode != 200 {
-		return met, error_status(res.StatusCode)
+	if md.Overview == "" {
+		md.Overview = other.Overview
 	}
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return movieMetadata{}, err
+}
+
+// Get credits for movie
+func (tmdb *TMDb) getMovieCredits(ctx context.Context, MediaId string) (Credits, error) {
+	var cred Credits
+	if err := tmdb.get(ctx, "/movie/"+MediaId+"/credits", nil, &cred); err != nil {
+		return Credits{}, err
 	}
-	if err := json.Unmarshal(body, &met); err != nil {
-		return movieMetadata{}, err
+	return cred.in_departments(tmdb.crew_departments).limited(tmdb.cast_limit, tmdb.crew_limit), nil
+}
+
+// Get basic information for Tv
+func (tmdb *TMDb) getTmdbTvDetails(ctx context.Context, MediaId string) (TVMetadata, error) {
+	var met TVMetadata
+	if err := tmdb.get(ctx, "/tv/"+MediaId, nil, &met); err != nil {
+		return TVMetadata{}, err
 	}
 	return met, nil
 }
 
 // Get credits for Tv
-func (tmdb *TMDb) getTmdbTvCredits(MediaId string) (tmdbCredits, error) {
-	var cred tmdbCredits
-	res, err := http.Get(base_url + "/tv/" + MediaId + "/credits?api_key=" + tmdb.api_key)
-	if err != nil {
-		return cred, err
-	}
-	if res.StatusCode != 200 {
-		return cred, error_status(res.StatusCode)
+func (tmdb *TMDb) getTmdbTvCredits(ctx context.Context, MediaId string) (Credits, error) {
+	var cred Credits
+	if err := tmdb.get(ctx, "/tv/"+MediaId+"/credits", nil, &cred); err != nil {
+		return Credits{}, err
 	}
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return tmdbCredits{}, err
-	}
-	if err := json.Unmarshal(body, &cred); err != nil {
-		return tmdbCredits{}, err
-	}
-	return cred, nil
+	return cred.in_departments(tmdb.crew_departments).limited(tmdb.cast_limit, tmdb.crew_limit), nil
 }
 
 // Transform the simplified movie metadata in JSON format
 // This output is rather arbitrary to our (Amahi's) needs and could be customized a little
+// Keys are matched case insensitively, so metadata stored from older versions
+// with Go style keys (e.g. "Release_date") is still accepted.
+// With a release region set (see SetReleaseRegion), the release dates of
+// movies are fetched from TMDb, so it makes a network request
 func (tmdb *TMDb) ToJSON(data string) (string, error) {
-	var f filtered_output
-	var det movieMetadata
+	var det MovieMetadata
 
 	if err := json.Unmarshal([]byte(data), &det); err != nil {
 		return "", err
 	}
 
-	f.Title = det.Title
-	f.Release_date = det.Release_date
-	if len(det.Release_date) > 4 {
-		f.Release_date = det.Release_date[0:4]
+	if det.Config == nil {
+		return "", ErrNoConfig
+	}
+	// tv ids are not movie ids, and shows have no theatrical releases
+	if tmdb.release_region != "" && det.Id != 0 && det.Media_type == "movie" {
+		// the primary date when the region's cannot be had
+		if releases, err := tmdb.MovieReleaseDates(int(det.Id)); err == nil {
+			if date := region_release_date(releases, tmdb.release_region); date != "" {
+				det.Release_date = date
+			}
+		}
 	}
-	// default width of the poster
-	size := det.poster_size("w154")
-	f.Artwork = det.Config.Images.Base_url + size + det.Poster_path
+	f := tmdb.filtered(det.Title, det.Release_date, det.Poster_path, det.Config)
 
 	metadata, err := json.Marshal(f)
 	if err != nil {
@@ -362,20 +718,264 @@ func (tmdb *TMDb) ToJSON(data string) (string, error) {
 	return string(metadata), nil
 }
 
-// return the requested size, the original if there are none
-// and the first one if the requested size does not exist
-func (md *movieMetadata) poster_size(size string) string {
-	if len(md.Config.Images.Poster_sizes) == 0 {
+// Use the date a movie opened in theaters in the given region (ISO 3166-1
+// code, e.g. "US") in the simplified output of ToJSON, instead of its
+// primary release date, which is often the one of its original country.
+// The primary date is kept when there is none for the region
+func (tmdb *TMDb) SetReleaseRegion(region string) {
+	tmdb.release_region = region
+}
+
+// Format the release date in the simplified output of ToJSON with the
+// given time layout, e.g. "2 January 2006" or "January 2, 2006", instead
+// of only the year. An empty layout goes back to the year
+func (tmdb *TMDb) SetDateLayout(layout string) {
+	tmdb.date_layout = layout
+}
+
+// The release date of the movie, an error if it has none or it is invalid
+func (md *MovieMetadata) ReleaseTime() (time.Time, error) {
+	return parse_date(md.Release_date)
+}
+
+// parse a date as given by TMDb, e.g. "2014-07-23"
+func parse_date(date string) (time.Time, error) {
+	return time.Parse("2006-01-02", date)
+}
+
+// the simplified output for a movie, with the artwork resolved with the config
+func (tmdb *TMDb) filtered(title, release_date, poster_path string, config *Config) filtered_output {
+	var f filtered_output
+	f.Title = title
+	f.Release_date = release_date
+	if t, err := parse_date(release_date); err == nil && tmdb.date_layout != "" {
+		f.Release_date = t.Format(tmdb.date_layout)
+	} else if len(release_date) > 4 {
+		f.Release_date = release_date[0:4]
+	}
+	if poster_path == "" {
+		// no artwork rather than a URL without a file
+		return f
+	}
+	size := default_poster_size
+	if tmdb.sizes.Poster != "" {
+		size = tmdb.sizes.Poster
+	}
+	f.Artwork = config.Images.Base_url + image_size(config.Images.Poster_sizes, size) + poster_path
+	return f
+}
+
+// Search for a movie like MovieData does and return the top n results in
+// the simplified JSON format of ToJSON, as an array, e.g. for the user to
+// pick the right one
+func (tmdb *TMDb) CandidatesJSON(media_name string, n int) (string, error) {
+	ctx := context.Background()
+	results, err := tmdb.searchMovie(ctx, media_name, 0)
+	if err != nil {
+		return "", err
+	}
+	config, err := tmdb.getConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	candidates := []filtered_output{}
+	for i := 0; i < n && i < len(results.Results); i++ {
+		r := results.Results[i]
+		candidates = append(candidates, tmdb.filtered(r.Title, r.Release_date, r.Poster_path, config))
+	}
+	metadata, err := json.Marshal(candidates)
+	if err != nil {
+		return "", err
+	}
+	return string(metadata), nil
+}
+
+// return the requested size out of the available sizes, the original
+// if there are none and the first one if the requested size does not exist
+func image_size(sizes []string, size string) string {
+	if len(sizes) == 0 {
 		return "original"
 	}
-	for i := range md.Config.Images.Poster_sizes {
-		if md.Config.Images.Poster_sizes[i] == size {
+	for i := range sizes {
+		if sizes[i] == size {
 			return size
 		}
 	}
-	return md.Config.Images.Poster_sizes[0]
+	return sizes[0]
+}
+
+// set the page query parameter, left out when no specific page is requested
+func set_page(params url.Values, page int) {
+	if page > 0 {
+		params.Set("page", strconv.Itoa(page))
+	}
+}
+
+// TMDb silently ignores sub-resources appended beyond this many
+const max_appends = 20
+
+// set the append_to_response query parameter, failing rather than having
+// TMDb drop the sub-resources beyond its limit
+func set_appends(params url.Values, appends ...string) error {
+	if len(appends) > max_appends {
+		return errors.New(fmt.Sprintf("Too many sub-resources appended (%d), TMDb allows at most %d in append_to_response", len(appends), max_appends))
+	}
+	if len(appends) > 0 {
+		params.Set("append_to_response", strings.Join(appends, ","))
+	}
+	return nil
+}
+
+// Get the given path of the TMDb API with the query parameters and
+// decode the JSON response into v, within the context and the timeout
+// for the kind of request
+func (tmdb *TMDb) get(ctx context.Context, path string, params url.Values, v interface{}) error {
+	if timeout := tmdb.timeout_for(path); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	query := url.Values{}
+	for k := range params {
+		query[k] = params[k]
+	}
+	if tmdb.language != "" && query.Get("language") == "" {
+		query.Set("language", tmdb.language)
+	}
+	tmdb.add_extra_params(ctx, query)
+	key := cache_key(path, query)
+	if tmdb.cache != nil && key != "" && tmdb.use_cached(ctx) {
+		if body, ok := tmdb.cache.Get(key); ok {
+			return json.Unmarshal(body, v)
+		}
+	}
+	query.Set("api_key", tmdb.api_key)
+	// the HTTP status of the last response, reported once retries were made
+	last_status := 0
+	retried := func(err error, attempts int) error {
+		return &RetryError{err, attempts, last_status}
+	}
+	for attempt := 0; ; attempt++ {
+		if tmdb.limiter != nil {
+			if err := tmdb.limiter.wait(ctx); err != nil {
+				if attempt > 0 {
+					return retried(err, attempt)
+				}
+				return err
+			}
+		}
+		body, res, err := tmdb.do(ctx, base_url+path+"?"+query.Encode())
+		if err != nil {
+			if attempt > 0 {
+				return retried(err, attempt+1)
+			}
+			return err
+		}
+		last_status = res.StatusCode
+		if res.StatusCode == 200 && is_json(res.Header, body) {
+			if tmdb.cache != nil && key != "" {
+				tmdb.cache.Set(key, body)
+			}
+			return json.Unmarshal(body, v)
+		}
+		var err_response error
+		if !is_json(res.Header, body) {
+			// e.g. the HTML page served during maintenance
+			err_response = ErrServiceUnavailable
+		} else {
+			err_response = response_error(res.StatusCode, body)
+		}
+		if attempt < tmdb.retries && retryable(res.StatusCode) {
+			wait := backoff(attempt, res.Header)
+			if !tmdb.fits_deadline(ctx, wait) {
+				// the retry would be doomed, fail with this response
+				// without spending the budget
+				return retried(err_response, attempt+1)
+			}
+			if tmdb.budget.take() {
+				if err := tmdb.sleep(ctx, wait); err != nil {
+					return retried(err, attempt+1)
+				}
+				continue
+			}
+		}
+		if attempt > 0 {
+			return retried(err_response, attempt+1)
+		}
+		return err_response
+	}
+}
+
+// make a GET request and read its response, which is closed
+func (tmdb *TMDb) do(ctx context.Context, url string) ([]byte, *http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := tmdb.http_client().Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, res, err
+	}
+	return body, res, nil
+}
+
+// whether a response is JSON, as opposed to e.g. an HTML error page
+func is_json(header http.Header, body []byte) bool {
+	if strings.Contains(header.Get("Content-Type"), "html") {
+		return false
+	}
+	body = bytes.TrimSpace(body)
+	return len(body) > 0 && (body[0] == '{' || body[0] == '[')
+}
+
+// error body returned by TMDb along with a failed status
+type tmdbStatus struct {
+	Status_code    int
+	Status_message string
+}
+
+// TMDb's status code for resources that do not exist
+const status_not_found = 34
+
+// the error for a failed response, from the status message in the body when present
+func response_error(status int, body []byte) error {
+	var st tmdbStatus
+	if err := json.Unmarshal(body, &st); err != nil || st.Status_message == "" {
+		return error_status(status)
+	}
+	if st.Status_code == status_not_found {
+		return ErrNotFound
+	}
+	return errors.New(fmt.Sprintf("Status Code %d received from TMDb: %s", status, st.Status_message))
 }
 
 func error_status(status int) error {
 	return errors.New(fmt.Sprintf("Status Code %d received from TMDb", status))
 }
+
+// A TMDb id. Decodes from both JSON numbers and strings holding a number,
+// as ids occasionally come as strings (e.g. in re-ingested metadata)
+type ID int
+
+func (id *ID) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" || s == "" {
+		*id = 0
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return errors.New("Invalid TMDb id " + string(data))
+	}
+	*id = ID(n)
+	return nil
+}
+
+func (id ID) String() string {
+	return strconv.Itoa(int(id))
+}
@@ -0,0 +1,166 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// sends the requests to a test server instead of TMDb
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = rt.target.Scheme
+	req.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// a TMDb whose requests are answered by the handler, and the function to
+// close its server
+func test_tmdb(t *testing.T, handler http.HandlerFunc) (*TMDb, func()) {
+	server := httptest.NewServer(handler)
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := Init("key")
+	db.SetHTTPClient(&http.Client{Transport: redirectTransport{target}})
+	return db, server.Close
+}
+
+func TestIDUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		json    string
+		id      ID
+		invalid bool
+	}{
+		{`123`, 123, false},
+		{`"123"`, 123, false},
+		{`null`, 0, false},
+		{`""`, 0, false},
+		{`"abc"`, 0, true},
+		{`12.5`, 0, true},
+	}
+	for _, test := range tests {
+		var v struct {
+			Id ID
+		}
+		err := json.Unmarshal([]byte(`{"id":`+test.json+`}`), &v)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("%s: expected an error, got id %d", test.json, v.Id)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.json, err)
+		} else if v.Id != test.id {
+			t.Errorf("%s: got id %d, want %d", test.json, v.Id, test.id)
+		}
+	}
+}
+
+func TestNoResultsMismatch(t *testing.T) {
+	tests := []struct {
+		json string
+		err  error
+		ok   bool
+	}{
+		{`{"page": 1, "results": [{"id": 1}], "total_results": 1}`, nil, true},
+		{`{"page": 1, "results": [], "total_results": 0}`, ErrNoResults, false},
+		// TMDb counting results it does not return
+		{`{"page": 1, "results": [], "total_results": 3}`, nil, false},
+		{`{"page": 1, "total_results": 3}`, nil, false},
+	}
+	for _, test := range tests {
+		var resp Response
+		if err := json.Unmarshal([]byte(test.json), &resp); err != nil {
+			t.Fatalf("%s: %v", test.json, err)
+		}
+		err := resp.no_results()
+		switch {
+		case test.ok && err != nil:
+			t.Errorf("%s: unexpected error %v", test.json, err)
+		case !test.ok && err == nil:
+			t.Errorf("%s: expected an error", test.json)
+		case test.err != nil && err != test.err:
+			t.Errorf("%s: got %v, want %v", test.json, err, test.err)
+		case !test.ok && test.err == nil && err == ErrNoResults:
+			t.Errorf("%s: mismatched totals reported as no results", test.json)
+		}
+	}
+}
+
+func TestToJSONNullPoster(t *testing.T) {
+	db := Init("key")
+	data := `{"id": 1, "media_type": "movie", "title": "Obscure", "release_date": "1999-05-01", "poster_path": null,
+		"config": {"images": {"base_url": "http://image.tmdb.org/t/p/", "poster_sizes": ["w92", "w154", "original"]}}}`
+	out, err := db.ToJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f filtered_output
+	if err := json.Unmarshal([]byte(out), &f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Artwork != "" {
+		t.Errorf("got artwork %q for a movie without poster, want none", f.Artwork)
+	}
+	if f.Title != "Obscure" || f.Release_date != "1999" {
+		t.Errorf("got %q (%q), want Obscure (1999)", f.Title, f.Release_date)
+	}
+}
+
+func TestToJSONReleaseRegionTV(t *testing.T) {
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request for %s", r.URL.Path)
+		http.NotFound(w, r)
+	})
+	defer done()
+	db.SetReleaseRegion("US")
+	data := `{"id": 1399, "media_type": "tv", "title": "Show", "release_date": "2011-04-17",
+		"config": {"images": {"base_url": "http://image.tmdb.org/t/p/", "poster_sizes": ["original"]}}}`
+	out, err := db.ToJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f filtered_output
+	if err := json.Unmarshal([]byte(out), &f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Release_date != "2011" {
+		t.Errorf("got release date %q, want 2011", f.Release_date)
+	}
+}
+
+func TestPreloadedConfigWithoutFetch(t *testing.T) {
+	requests := 0
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"images": {"base_url": "http://image.tmdb.org/t/p/", "poster_sizes": ["original"]}}`))
+	})
+	defer done()
+	db.SetFetchConfig(false)
+
+	if config := db.metadata_config(context.Background()); config != nil || requests != 0 {
+		t.Errorf("got config %v after %d requests, want none", config, requests)
+	}
+	if err := db.PreloadConfig(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	config := db.metadata_config(context.Background())
+	if config == nil || config.Images.Base_url != "http://image.tmdb.org/t/p/" || requests != 1 {
+		t.Errorf("got config %v after %d requests, want the preloaded one", config, requests)
+	}
+}
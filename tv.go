@@ -0,0 +1,246 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Tv metadata structure, as returned in JSON by TVData
+type TVMetadata struct {
+	Id                 ID      `json:"id"`
+	Media_type         string  `json:"media_type"`
+	Backdrop_path      string  `json:"backdrop_path"`
+	Poster_path        string  `json:"poster_path"`
+	Credits            Credits `json:"credits"`
+	Config             *Config `json:"config"`
+	Name               string  `json:"name"`
+	Original_name      string  `json:"original_name"`
+	Overview           string  `json:"overview"`
+	First_air_date     string  `json:"first_air_date"`
+	Number_of_seasons  int     `json:"number_of_seasons"`
+	Number_of_episodes int     `json:"number_of_episodes"`
+	// all the seasons, including specials as season 0 when the show has
+	// them (these are not counted in Number_of_seasons)
+	Seasons []TVSeasonSummary `json:"seasons"`
+	// the latest episode aired and the next one to air, nil when there is
+	// none (e.g. for ended shows)
+	Last_episode_to_air *TVEpisodeSummary `json:"last_episode_to_air"`
+	Next_episode_to_air *TVEpisodeSummary `json:"next_episode_to_air"`
+	// SchemaVersion when fetched
+	Schema_version int `json:"schema_version"`
+}
+
+// An episode as referenced in the Tv show details
+type TVEpisodeSummary struct {
+	Id             ID     `json:"id"`
+	Air_date       string `json:"air_date"`
+	Episode_number int    `json:"episode_number"`
+	Season_number  int    `json:"season_number"`
+	Name           string `json:"name"`
+	Overview       string `json:"overview"`
+	Still_path     string `json:"still_path"`
+}
+
+// The air date of the episode, an error if it has none or it is invalid
+func (e *TVEpisodeSummary) AirTime() (time.Time, error) {
+	if e == nil {
+		return time.Time{}, errors.New("No episode to air")
+	}
+	return parse_date(e.Air_date)
+}
+
+// A season as listed in the Tv show details
+type TVSeasonSummary struct {
+	Id            ID     `json:"id"`
+	Air_date      string `json:"air_date"`
+	Episode_count int    `json:"episode_count"`
+	Name          string `json:"name"`
+	Overview      string `json:"overview"`
+	Poster_path   string `json:"poster_path"`
+	Season_number int    `json:"season_number"`
+}
+
+// The call for getting Tv show data, media_name is the (plain) name of
+// the show to be retrieved without year or other information. The
+// metadata includes the list of seasons
+func (tmdb *TMDb) TVData(media_name string) (string, error) {
+	ctx := context.Background()
+	results, err := tmdb.searchTmdbTv(ctx, media_name, 0)
+	if err != nil {
+		return "", err
+	}
+	if err := results.no_results(); err != nil {
+		return "", err
+	}
+	id := results.Results[0].Id.String()
+	tv_details, err := tmdb.getTmdbTvDetails(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	sections := sectionErrors{lenient: tmdb.lenient}
+	tv_details.Credits, err = tmdb.getTmdbTvCredits(ctx, id)
+	if !sections.ok("credits", err) {
+		return "", err
+	}
+	tv_details.Config = tmdb.metadata_config(ctx)
+	tv_details.Id = results.Results[0].Id
+	tv_details.Media_type = "tv"
+	tv_details.Schema_version = SchemaVersion
+
+	metadata, err := json.Marshal(tv_details)
+	if err != nil {
+		return "", err
+	}
+	return string(metadata), sections.err()
+}
+
+// A season of a Tv show, with its episodes
+type TVSeason struct {
+	Id            ID
+	Air_date      string
+	Name          string
+	Overview      string
+	Poster_path   string
+	Season_number int
+	Episodes      []TVEpisode
+	// only fetched when asked for
+	Credits Credits
+}
+
+// A single episode of a Tv show
+type TVEpisode struct {
+	Id             ID
+	Air_date       string
+	Episode_number int
+	Season_number  int
+	Name           string
+	Overview       string
+	Still_path     string
+	Vote_average   float64
+	Vote_count     int
+	// position of the episode within an episode group
+	Order int
+}
+
+// Get a season of a Tv show, including its episodes
+func (tmdb *TMDb) TVSeason(tv_id, season int) (TVSeason, error) {
+	return tmdb.tv_season(tv_id, season, nil)
+}
+
+// Get a season of a Tv show like TVSeason, and the season cast and crew in
+// the same request
+func (tmdb *TMDb) TVSeasonWithCredits(tv_id, season int) (TVSeason, error) {
+	params := url.Values{}
+	if err := set_appends(params, "credits"); err != nil {
+		return TVSeason{}, err
+	}
+	return tmdb.tv_season(tv_id, season, params)
+}
+
+func (tmdb *TMDb) tv_season(tv_id, season int, params url.Values) (TVSeason, error) {
+	var s TVSeason
+	if err := tmdb.get(context.Background(), "/tv/"+strconv.Itoa(tv_id)+"/season/"+strconv.Itoa(season), params, &s); err != nil {
+		return TVSeason{}, err
+	}
+	return s, nil
+}
+
+// Get a single episode of a Tv show
+func (tmdb *TMDb) TVEpisode(tv_id, season, episode int) (TVEpisode, error) {
+	var e TVEpisode
+	if err := tmdb.get(context.Background(), "/tv/"+strconv.Itoa(tv_id)+"/season/"+strconv.Itoa(season)+"/episode/"+strconv.Itoa(episode), nil, &e); err != nil {
+		return TVEpisode{}, err
+	}
+	return e, nil
+}
+
+// Get the posters of a season of a Tv show
+func (tmdb *TMDb) TVSeasonImages(tv_id, season int) (Images, error) {
+	var images Images
+	if err := tmdb.get(context.Background(), "/tv/"+strconv.Itoa(tv_id)+"/season/"+strconv.Itoa(season)+"/images", nil, &images); err != nil {
+		return Images{}, err
+	}
+	return images, nil
+}
+
+// The age rating of a Tv show in a country
+type ContentRating struct {
+	Iso_3166_1 string
+	Rating     string
+}
+
+// The age ratings of a Tv show in all the countries it was rated in
+type ContentRatings []ContentRating
+
+// The rating for the given country (e.g. "US"), empty if the show was not
+// rated there
+func (ratings ContentRatings) Country(country string) string {
+	for i := range ratings {
+		if ratings[i].Iso_3166_1 == country {
+			return ratings[i].Rating
+		}
+	}
+	return ""
+}
+
+// Get the per country age ratings of a Tv show
+func (tmdb *TMDb) TVContentRatings(tv_id int) (ContentRatings, error) {
+	var resp struct {
+		Results ContentRatings
+	}
+	if err := tmdb.get(context.Background(), "/tv/"+strconv.Itoa(tv_id)+"/content_ratings", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// An alternate ordering of the episodes of a Tv show, e.g. the DVD order
+type EpisodeGroup struct {
+	Id          string
+	Name        string
+	Description string
+	// 1 original air date, 2 absolute, 3 DVD, 4 digital, 5 story arc,
+	// 6 production, 7 TV
+	Type          int
+	Episode_count int
+	Group_count   int
+	// the groups (e.g. the seasons for the ordering) with their episodes,
+	// only filled in by TVEpisodeGroup
+	Groups []EpisodeGroupPart
+}
+
+// A group within an episode group, with its episodes in order
+type EpisodeGroupPart struct {
+	Id       string
+	Name     string
+	Order    int
+	Episodes []TVEpisode
+}
+
+// Get the episode groups available for a Tv show, empty if there are none
+func (tmdb *TMDb) TVEpisodeGroups(tv_id int) ([]EpisodeGroup, error) {
+	var resp struct {
+		Results []EpisodeGroup
+	}
+	if err := tmdb.get(context.Background(), "/tv/"+strconv.Itoa(tv_id)+"/episode_groups", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// Get the full structure of an episode group, with all its groups and episodes
+func (tmdb *TMDb) TVEpisodeGroup(group_id string) (EpisodeGroup, error) {
+	var group EpisodeGroup
+	if err := tmdb.get(context.Background(), "/tv/episode_group/"+url.PathEscape(group_id), nil, &group); err != nil {
+		return EpisodeGroup{}, err
+	}
+	return group, nil
+}
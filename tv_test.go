@@ -0,0 +1,47 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestTVSeasonCredits(t *testing.T) {
+	var appends []string
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		appends = append(appends, r.URL.Query().Get("append_to_response"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 1, "season_number": 2}`))
+	})
+	defer done()
+
+	if _, err := db.TVSeason(1399, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.TVSeasonWithCredits(1399, 2); err != nil {
+		t.Fatal(err)
+	}
+	if len(appends) != 2 || appends[0] != "" || appends[1] != "credits" {
+		t.Errorf("got appends %q, want none then credits", appends)
+	}
+}
+
+func TestTVEpisodeGroupEscape(t *testing.T) {
+	var path string
+	db, done := test_tmdb(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "a b"}`))
+	})
+	defer done()
+
+	if _, err := db.TVEpisodeGroup("a b"); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/3/tv/episode_group/a b" {
+		t.Errorf("got path %q, want /3/tv/episode_group/a b", path)
+	}
+}
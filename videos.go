@@ -0,0 +1,127 @@
+// Copyright 2014, Amahi.  All rights reserved.
+// Use of this source code is governed by the
+// license that can be found in the LICENSE file.
+
+package tmdb
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// A video (trailer, teaser, clip, ...) hosted on YouTube or Vimeo
+type Video struct {
+	Id         string
+	Iso_639_1  string
+	Iso_3166_1 string
+	// the id of the video on its site
+	Key  string
+	Name string
+	// "YouTube" or "Vimeo"
+	Site string
+	// height of the video, e.g. 1080
+	Size int
+	// "Trailer", "Teaser", "Clip", "Featurette", ...
+	Type         string
+	Official     bool
+	Published_at string
+}
+
+// The URL to watch the video on YouTube, empty if not hosted there
+func (v Video) YouTubeURL() string {
+	if v.Site != "YouTube" || v.Key == "" {
+		return ""
+	}
+	return "https://www.youtube.com/watch?v=" + v.Key
+}
+
+// The thumbnail of the video on YouTube, empty if not hosted there
+func (v Video) YouTubeThumbnail() string {
+	if v.Site != "YouTube" || v.Key == "" {
+		return ""
+	}
+	return "https://img.youtube.com/vi/" + v.Key + "/hqdefault.jpg"
+}
+
+// The videos of a movie or tv show
+type Videos []Video
+
+// The primary trailer on YouTube, the first official one if any, false
+// if there is no trailer on YouTube
+func (videos Videos) Trailer() (Video, bool) {
+	found := false
+	var trailer Video
+	for _, v := range videos {
+		if v.Type != "Trailer" || v.Site != "YouTube" {
+			continue
+		}
+		if v.Official {
+			return v, true
+		}
+		if !found {
+			trailer, found = v, true
+		}
+	}
+	return trailer, found
+}
+
+// The YouTube thumbnail of the primary trailer, empty if there is no
+// trailer on YouTube
+func (videos Videos) TrailerThumbnail() string {
+	trailer, ok := videos.Trailer()
+	if !ok {
+		return ""
+	}
+	return trailer.YouTubeThumbnail()
+}
+
+// Returned when a movie has no trailer on YouTube
+var ErrNoTrailer = errors.New("No trailer found at TMDb")
+
+// the videos in the given language
+func (videos Videos) language(lang string) Videos {
+	var in_lang Videos
+	for _, v := range videos {
+		if v.Iso_639_1 == lang {
+			in_lang = append(in_lang, v)
+		}
+	}
+	return in_lang
+}
+
+// Get the primary trailer of a movie on YouTube in the given language
+// (ISO 639-1 code, e.g. "de"), or else in English, or else in any
+// language. Returns ErrNoTrailer if there is none
+func (tmdb *TMDb) PreferredTrailer(movie_id int, lang string) (Video, error) {
+	videos, err := tmdb.MovieVideos(movie_id)
+	if err != nil {
+		return Video{}, err
+	}
+	for _, candidates := range []Videos{videos.language(lang), videos.language("en"), videos} {
+		if trailer, ok := candidates.Trailer(); ok {
+			return trailer, nil
+		}
+	}
+	return Video{}, ErrNoTrailer
+}
+
+// Get the videos of a movie, empty if it has none
+func (tmdb *TMDb) MovieVideos(movie_id int) (Videos, error) {
+	return tmdb.videos("/movie/" + strconv.Itoa(movie_id) + "/videos")
+}
+
+// Get the videos of a Tv show, empty if it has none
+func (tmdb *TMDb) TVVideos(tv_id int) (Videos, error) {
+	return tmdb.videos("/tv/" + strconv.Itoa(tv_id) + "/videos")
+}
+
+func (tmdb *TMDb) videos(path string) (Videos, error) {
+	var resp struct {
+		Results Videos
+	}
+	if err := tmdb.get(context.Background(), path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}